@@ -0,0 +1,303 @@
+// Package bridge mirrors the commands sent on a [conductor.Conductor] to the conductors
+// living in other processes, over TCP, so that remote tags behave like local ones.
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+	"git.sr.ht/~blallo/conductor/internal/queue"
+	"git.sr.ht/~blallo/conductor/internal/seen"
+)
+
+const (
+	originPrefix      = "bridge:"
+	defaultHeartbeat  = time.Second
+	defaultMaxBackoff = 30 * time.Second
+	minBackoff        = 100 * time.Millisecond
+	seenSize          = 4096
+)
+
+// Config tunes the behavior of a [Bridge]. The zero value is usable.
+type Config[T any] struct {
+	// Codecs are used to encode the commands on the wire, in order of preference.
+	// Defaults to [conductor.JSONCodec].
+	Codecs []conductor.Codec[T]
+	// Node identifies this side of the bridge. Defaults to the hostname and pid.
+	Node string
+	// Heartbeat is the interval between two pings sent to a peer. Defaults to 1s.
+	Heartbeat time.Duration
+	// Timeout is how long a silent peer is waited for, before dropping the connection.
+	// Defaults to three heartbeats.
+	Timeout time.Duration
+	// MaxBackoff caps the time waited between two reconnection attempts. Defaults
+	// to 30s.
+	MaxBackoff time.Duration
+	// OnError is called with the errors ending the exchange with a peer, e.g. a
+	// failed handshake or a command that cannot be decoded. Defaults to ignoring
+	// them.
+	OnError func(error)
+}
+
+// Bridge mirrors the commands sent on a [conductor.Conductor] to its peers, and delivers
+// the commands received from them, preserving their tags. A command is never sent back
+// to the peer it came from, and commands already seen are discarded, so that peers
+// may be connected in any topology.
+type Bridge[T any] struct {
+	c      conductor.Conductor[T]
+	cfg    Config[T]
+	codecs map[string]conductor.Codec[T]
+	names  []string
+	seen   *seen.Set
+}
+
+// New creates a [Bridge] for the given [conductor.Conductor]. It does nothing until
+// [Bridge.Serve] or [Bridge.Connect] are called.
+func New[T any](c conductor.Conductor[T], cfg Config[T]) *Bridge[T] {
+	if len(cfg.Codecs) == 0 {
+		cfg.Codecs = []conductor.Codec[T]{conductor.JSONCodec[T]()}
+	}
+	if cfg.Node == "" {
+		hostname, _ := os.Hostname()
+		cfg.Node = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = defaultHeartbeat
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * cfg.Heartbeat
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(error) {}
+	}
+
+	b := &Bridge[T]{
+		c:      c,
+		cfg:    cfg,
+		codecs: make(map[string]conductor.Codec[T], len(cfg.Codecs)),
+		seen:   seen.New(seenSize),
+	}
+	for _, codec := range cfg.Codecs {
+		b.codecs[codec.Name()] = codec
+		b.names = append(b.names, codec.Name())
+	}
+
+	return b
+}
+
+// Serve accepts peers on the given [net.Listener]. It blocks until the conductor is
+// done, closing the listener, or until accepting a connection fails.
+func (b *Bridge[T]) Serve(l net.Listener) error {
+	go func() {
+		<-b.c.Done()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if b.c.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			w := newWire(conn, b.cfg.Timeout)
+			hello, codec, err := welcome(w, b.cfg.Node, b.names)
+			if err != nil {
+				b.cfg.OnError(err)
+				return
+			}
+			if err := b.run(w, b.codecs[codec], hello.Node); err != nil {
+				b.cfg.OnError(err)
+			}
+		}()
+	}
+}
+
+// Connect dials a peer at the given address, reconnecting with an exponential backoff
+// whenever the connection is lost. It blocks until the conductor is done, or until the
+// peer refuses the handshake or picks an unknown codec, in which case an error wrapping
+// [ErrHandshake] is returned.
+func (b *Bridge[T]) Connect(network, address string) error {
+	dialer := &net.Dialer{Timeout: b.cfg.Timeout}
+	backoff := minBackoff
+
+	for {
+		if conn, err := dialer.DialContext(b.c, network, address); err == nil {
+			w := newWire(conn, b.cfg.Timeout)
+			welcome, err := hello(w, b.cfg.Node, b.names)
+			if err == nil {
+				backoff = minBackoff
+				err = b.run(w, b.codecs[welcome.Codec], welcome.Node)
+			}
+			conn.Close()
+
+			if errors.Is(err, ErrHandshake) {
+				return err
+			}
+			if err != nil {
+				b.cfg.OnError(err)
+			}
+		}
+
+		select {
+		case <-b.c.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > b.cfg.MaxBackoff {
+			backoff = b.cfg.MaxBackoff
+		}
+	}
+}
+
+// run exchanges commands with a peer, until the connection is lost or the conductor
+// is done.
+func (b *Bridge[T]) run(w *wire, codec conductor.Codec[T], peer string) error {
+	if codec == nil {
+		return fmt.Errorf("%w: unknown codec", ErrHandshake)
+	}
+
+	envelopes, stop := conductor.Follow(b.c)
+	defer stop()
+
+	// XXX: the commands read are delivered by a goroutine of their own, so that the
+	// reader never waits for the local listeners nor, through the follower, for the
+	// writer: otherwise, two peers writing to each other at once may both end up
+	// blocked on a full connection, with no one reading it.
+	received := queue.New[conductor.Envelope[T]]()
+	delivered := make(chan struct{})
+	go func() {
+		defer close(delivered)
+		b.deliver(received)
+	}()
+
+	done := make(chan struct{})
+	errs := make(chan error, 2)
+	go func() {
+		defer received.Close()
+		errs <- b.read(w, codec, peer, received)
+	}()
+	go func() { errs <- b.write(w, codec, peer, envelopes, done) }()
+
+	// XXX: stop following before waiting for the deliverer, that may be delivering a
+	// command the writer is not there to take anymore.
+	err := <-errs
+	stop()
+	close(done)
+	w.conn.Close()
+	<-errs
+	<-delivered
+
+	return err
+}
+
+// deliver hands the commands received from a peer to the conductor, until the reader
+// is done and all of them were delivered.
+func (b *Bridge[T]) deliver(received *queue.Queue[conductor.Envelope[T]]) {
+	for {
+		env, ok := received.Pop()
+		if !ok {
+			return
+		}
+		conductor.Deliver(b.c, env)
+	}
+}
+
+func (b *Bridge[T]) read(w *wire, codec conductor.Codec[T], peer string, received *queue.Queue[conductor.Envelope[T]]) error {
+	for {
+		f, err := w.read()
+		if err != nil {
+			return err
+		}
+
+		if f.Type != frameCmd || !b.seen.Add(f.ID) {
+			continue
+		}
+
+		cmd, err := codec.Decode(f.Cmd)
+		if err != nil {
+			return err
+		}
+
+		env := conductor.Envelope[T]{
+			ID:     f.ID,
+			Cmd:    cmd,
+			Time:   f.Time,
+			Origin: originPrefix + peer,
+			Meta:   f.Meta,
+		}
+		for _, tag := range f.Tags {
+			env.Tags = append(env.Tags, tag)
+		}
+		received.Push(env)
+	}
+}
+
+func (b *Bridge[T]) write(w *wire, codec conductor.Codec[T], peer string, envelopes <-chan conductor.Envelope[T], done <-chan struct{}) error {
+	ticker := time.NewTicker(b.cfg.Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case env, ok := <-envelopes:
+			if !ok {
+				return nil
+			}
+			if !forwardable(env.Origin, peer) {
+				continue
+			}
+			b.seen.Add(env.ID)
+
+			data, err := codec.Encode(env.Cmd)
+			if err != nil {
+				return err
+			}
+
+			f := frame{
+				Type: frameCmd,
+				ID:   env.ID,
+				Time: env.Time,
+				Meta: env.Meta,
+				Cmd:  data,
+			}
+			for _, tag := range env.Tags {
+				f.Tags = append(f.Tags, fmt.Sprint(tag))
+			}
+			if err := w.write(f); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := w.write(frame{Type: framePing}); err != nil {
+				return err
+			}
+		case <-done:
+			return nil
+		case <-b.c.Done():
+			return nil
+		}
+	}
+}
+
+// forwardable tells if a command with the given origin should be sent to the peer:
+// only the commands sent locally and those coming from other peers are.
+func forwardable(origin, peer string) bool {
+	if origin == "" {
+		return true
+	}
+	return strings.HasPrefix(origin, originPrefix) && origin != originPrefix+peer
+}
@@ -0,0 +1,267 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+const (
+	failureTimeout = time.Second
+	successTimeout = 100 * time.Millisecond
+)
+
+func config(node string) Config[string] {
+	return Config[string]{
+		Node:      node,
+		Heartbeat: 20 * time.Millisecond,
+	}
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func tagged(t *testing.T) conductor.Conductor[string] {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return conductor.TaggedFromContext[string](ctx)
+}
+
+func expect(t *testing.T, lis <-chan string, want string) {
+	t.Helper()
+
+	select {
+	case cmd := <-lis:
+		if cmd != want {
+			t.Fatalf("Unexpected cmd: %s", cmd)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+}
+
+func expectNothing(t *testing.T, lis <-chan string) {
+	t.Helper()
+
+	select {
+	case cmd := <-lis:
+		t.Fatalf("Unexpected cmd: %s", cmd)
+	case <-time.After(successTimeout):
+	}
+}
+
+func TestBridge_mirrorsSends(t *testing.T) {
+	a, b := tagged(t), tagged(t)
+
+	redA := conductor.WithTag(a, "red").Cmd()
+	redB := conductor.WithTag(b, "red").Cmd()
+	blueB := conductor.WithTag(b, "blue").Cmd()
+
+	l := listen(t)
+	go New(a, config("a")).Serve(l)
+	go New(b, config("b")).Connect("tcp", l.Addr().String())
+
+	// XXX: the bridge mirrors only what is sent after the peers connected.
+	time.Sleep(successTimeout)
+
+	conductor.Send(a, "red")("pause")
+	expect(t, redA, "pause")
+	expect(t, redB, "pause")
+	expectNothing(t, blueB)
+
+	conductor.Send(b)("stop")
+	expect(t, redA, "stop")
+	expect(t, redB, "stop")
+	expect(t, blueB, "stop")
+
+	// Nothing bounces back.
+	expectNothing(t, redA)
+	expectNothing(t, redB)
+}
+
+func TestBridge_hub(t *testing.T) {
+	hub, a, b := tagged(t), tagged(t), tagged(t)
+
+	lisA := a.Cmd()
+	lisB := b.Cmd()
+	lisHub := hub.Cmd()
+
+	l := listen(t)
+	go New(hub, config("hub")).Serve(l)
+	go New(a, config("a")).Connect("tcp", l.Addr().String())
+	go New(b, config("b")).Connect("tcp", l.Addr().String())
+
+	time.Sleep(successTimeout)
+
+	conductor.Send(a)("ciao")
+	expect(t, lisA, "ciao")
+	expect(t, lisHub, "ciao")
+	expect(t, lisB, "ciao")
+
+	expectNothing(t, lisA)
+}
+
+func TestBridge_twoWayLoad(t *testing.T) {
+	const n = 5000
+
+	a, b := tagged(t), tagged(t)
+	lisA := a.Cmd()
+	lisB := b.Cmd()
+
+	l := listen(t)
+	cfgA, cfgB := config("a"), config("b")
+	cfgA.Heartbeat, cfgB.Heartbeat = failureTimeout, failureTimeout
+	go New(a, cfgA).Serve(l)
+	go New(b, cfgB).Connect("tcp", l.Addr().String())
+
+	time.Sleep(successTimeout)
+
+	// Each side receives its own commands and the ones of the other side. They are
+	// big enough to fill the buffers of the connection.
+	received := make(chan int, 2)
+	count := func(lis <-chan string) {
+		for i := 0; i < 2*n; i++ {
+			select {
+			case <-lis:
+			case <-time.After(5 * time.Second):
+				received <- i
+				return
+			}
+		}
+		received <- 2 * n
+	}
+	go count(lisA)
+	go count(lisB)
+
+	cmd := strings.Repeat("x", 1024)
+	for _, c := range []conductor.Conductor[string]{a, b} {
+		go func(c conductor.Conductor[string]) {
+			for i := 0; i < n; i++ {
+				conductor.Send(c)(cmd)
+			}
+		}(c)
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := <-received; got != 2*n {
+			t.Fatalf("Stalled after %d commands of %d", got, 2*n)
+		}
+	}
+}
+
+func TestBridge_reconnects(t *testing.T) {
+	a := tagged(t)
+	b := tagged(t)
+	redB := conductor.WithTag(b, "red").Cmd()
+
+	l := listen(t)
+	addr := l.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := conductor.TaggedFromContext[string](ctx)
+	go New(first, config("a")).Serve(l)
+	go New(b, config("b")).Connect("tcp", addr)
+
+	time.Sleep(successTimeout)
+	cancel()
+
+	// XXX: the same port is reused, hoping nobody took it in the meantime.
+	time.Sleep(successTimeout)
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("Cannot listen again on %s: %s", addr, err)
+	}
+	go New(a, config("a")).Serve(l)
+
+	deadline := time.After(failureTimeout)
+	for {
+		conductor.Send(a, "red")("ciao")
+		select {
+		case cmd := <-redB:
+			if cmd != "ciao" {
+				t.Fatalf("Unexpected cmd: %s", cmd)
+			}
+			return
+		case <-time.After(successTimeout):
+		case <-deadline:
+			t.Fatal("Timeout")
+		}
+	}
+}
+
+func TestBridge_codecMismatch(t *testing.T) {
+	a, b := tagged(t), tagged(t)
+
+	l := listen(t)
+	reported := make(chan error, 1)
+	served := config("a")
+	served.OnError = func(err error) {
+		select {
+		case reported <- err:
+		default:
+		}
+	}
+	go New(a, served).Serve(l)
+
+	cfg := config("b")
+	cfg.Codecs = []conductor.Codec[string]{conductor.StringCodec[string]()}
+
+	done := make(chan error)
+	go func() { done <- New(b, cfg).Connect("tcp", l.Addr().String()) }()
+
+	for _, ch := range []chan error{done, reported} {
+		select {
+		case err := <-ch:
+			if !errors.Is(err, ErrHandshake) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		case <-time.After(failureTimeout):
+			t.Fatal("Timeout")
+		}
+	}
+}
+
+func TestBridge_silentPeer(t *testing.T) {
+	a := tagged(t)
+
+	l := listen(t)
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			w := newWire(conn, failureTimeout)
+			// Complete the handshake, then never say anything.
+			if _, _, err := welcome(w, "silent", []string{"json"}); err == nil {
+				accepted <- conn
+			}
+		}
+	}()
+
+	go New(a, config("a")).Connect("tcp", l.Addr().String())
+
+	for i := 0; i < 2; i++ {
+		select {
+		case conn := <-accepted:
+			defer conn.Close()
+		case <-time.After(failureTimeout):
+			t.Fatalf("Timeout waiting for connection %d", i)
+		}
+	}
+}
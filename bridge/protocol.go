@@ -0,0 +1,124 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ProtocolVersion is the version of the wire protocol spoken by the bridge. Peers
+// speaking a different version refuse to talk to each other.
+const ProtocolVersion = 1
+
+const (
+	frameHello   = "hello"
+	frameWelcome = "welcome"
+	frameError   = "error"
+	framePing    = "ping"
+	frameCmd     = "cmd"
+)
+
+// ErrHandshake is returned when two peers cannot agree on how to talk to each other.
+var ErrHandshake = errors.New("bridge handshake failed")
+
+// frame is the unit exchanged on the wire, encoded as a JSON line.
+type frame struct {
+	Type    string            `json:"type"`
+	Version int               `json:"version,omitempty"`
+	Node    string            `json:"node,omitempty"`
+	Codecs  []string          `json:"codecs,omitempty"`
+	Codec   string            `json:"codec,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	ID      string            `json:"id,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+	Time    time.Time         `json:"time"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Cmd     []byte            `json:"cmd,omitempty"`
+}
+
+type wire struct {
+	conn    net.Conn
+	enc     *json.Encoder
+	dec     *json.Decoder
+	timeout time.Duration
+}
+
+func newWire(conn net.Conn, timeout time.Duration) *wire {
+	return &wire{
+		conn:    conn,
+		enc:     json.NewEncoder(conn),
+		dec:     json.NewDecoder(conn),
+		timeout: timeout,
+	}
+}
+
+func (w *wire) write(f frame) error {
+	w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	return w.enc.Encode(f)
+}
+
+func (w *wire) read() (f frame, err error) {
+	w.conn.SetReadDeadline(time.Now().Add(w.timeout))
+	err = w.dec.Decode(&f)
+	return
+}
+
+// hello is the dialing side of the handshake.
+func hello(w *wire, node string, codecs []string) (welcome frame, err error) {
+	if err = w.write(frame{Type: frameHello, Version: ProtocolVersion, Node: node, Codecs: codecs}); err != nil {
+		return
+	}
+
+	if welcome, err = w.read(); err != nil {
+		return
+	}
+
+	switch {
+	case welcome.Type == frameError:
+		err = fmt.Errorf("%w: %s", ErrHandshake, welcome.Error)
+	case welcome.Type != frameWelcome:
+		err = fmt.Errorf("%w: unexpected %q frame", ErrHandshake, welcome.Type)
+	case welcome.Version != ProtocolVersion:
+		err = fmt.Errorf("%w: unsupported version %d", ErrHandshake, welcome.Version)
+	}
+
+	return
+}
+
+// welcome is the accepting side of the handshake. It picks the first of the given
+// codecs that is also known by the peer.
+func welcome(w *wire, node string, codecs []string) (hello frame, codec string, err error) {
+	if hello, err = w.read(); err != nil {
+		return
+	}
+
+	reject := func(reason string) error {
+		w.write(frame{Type: frameError, Error: reason})
+		return fmt.Errorf("%w: %s", ErrHandshake, reason)
+	}
+
+	if hello.Type != frameHello {
+		err = reject(fmt.Sprintf("unexpected %q frame", hello.Type))
+		return
+	}
+
+	if hello.Version != ProtocolVersion {
+		err = reject(fmt.Sprintf("unsupported version %d", hello.Version))
+		return
+	}
+
+	for _, ours := range codecs {
+		for _, theirs := range hello.Codecs {
+			if ours == theirs {
+				codec = ours
+				err = w.write(frame{Type: frameWelcome, Version: ProtocolVersion, Node: node, Codec: codec})
+				return
+			}
+		}
+	}
+
+	err = reject(fmt.Sprintf("no common codec in %v", hello.Codecs))
+	return
+}
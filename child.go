@@ -0,0 +1,57 @@
+package conductor
+
+import (
+	"context"
+	"sync"
+)
+
+// Child creates a [Conductor] of the same kind of the given one, with its own set of
+// listeners, that is canceled along with its parent or by calling the returned
+// function. The commands sent through the parent are received by the listeners of the
+// child too (and of its children, recursively), while the ones sent through the child
+// stay within its subtree. The commands fired by the policies of the parent are not
+// handed to the child, that fires its own ones when canceled. Once its context ends,
+// the child is detached from the parent.
+func Child[T any](conductor Conductor[T]) (Conductor[T], context.CancelFunc) {
+	if s, ok := subOf(conductor); ok {
+		child, cancel := Child[T](s.wrapped)
+		return Sub(child, s.prefix), cancel
+	}
+
+	ctx, cancel := context.WithCancel(conductor)
+
+	var parent *hub[T]
+	var child *hub[T]
+	var n node[T]
+	var c Conductor[T]
+	switch p := any(unwrap(conductor)).(type) {
+	case *simple[T]:
+		s := &simple[T]{
+			listeners: make(map[string]chan T),
+			mu:        new(sync.RWMutex),
+			ctx:       ctx,
+			logFile:   p.logFile,
+			hub:       newHub[T](),
+		}
+		parent, child, n, c = p.hub, s.hub, s, s
+	case *tagged[T]:
+		t := &tagged[T]{
+			tagged: make(map[any]*simple[T]),
+			mu:     new(sync.RWMutex),
+			ctx:    ctx,
+			hub:    newHub[T](),
+		}
+		parent, child, n, c = p.hub, t.hub, t, t
+	default:
+		panic("conductor not supported")
+	}
+
+	child.evictAfter.Store(parent.evictAfter.Load())
+	parent.addChild(child, n)
+	go func() {
+		<-ctx.Done()
+		parent.removeChild(child)
+	}()
+
+	return c, cancel
+}
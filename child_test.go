@@ -0,0 +1,133 @@
+package conductor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func expectCmd(t *testing.T, lis <-chan string, expected string) {
+	t.Helper()
+	select {
+	case cmd := <-lis:
+		if cmd != expected {
+			t.Fatalf("Expected %s, got %s", expected, cmd)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatalf("%s not received", expected)
+	}
+}
+
+func expectNoCmd(t *testing.T, lis <-chan string) {
+	t.Helper()
+	select {
+	case cmd := <-lis:
+		t.Fatalf("Unexpected: %s", cmd)
+	case <-time.After(successTimeout):
+	}
+}
+
+func TestChildSimple(t *testing.T) {
+	parent := Simple[string]()
+	child, cancel := Child(parent)
+	grandchild, cancelGrandchild := Child(child)
+	defer cancelGrandchild()
+
+	parentLis := parent.Cmd()
+	childLis := child.Cmd()
+	grandchildLis := grandchild.Cmd()
+
+	Send(parent)("all")
+	expectCmd(t, parentLis, "all")
+	expectCmd(t, childLis, "all")
+	expectCmd(t, grandchildLis, "all")
+
+	Send(child)("subtree")
+	expectCmd(t, childLis, "subtree")
+	expectCmd(t, grandchildLis, "subtree")
+	expectNoCmd(t, parentLis)
+
+	if keys := Listeners(parent)[""]; len(keys) != 1 {
+		t.Fatalf("Parent sees the listeners of the child: %v", keys)
+	}
+
+	cancel()
+	<-grandchild.Done()
+	time.Sleep(10 * time.Millisecond)
+
+	Send(parent)("after")
+	expectCmd(t, parentLis, "after")
+	expectNoCmd(t, childLis)
+	expectNoCmd(t, grandchildLis)
+}
+
+func TestChildTagged(t *testing.T) {
+	parent := Tagged[string]()
+	child, cancel := Child(parent)
+	defer cancel()
+
+	parentDB := WithTag(parent, "db").Cmd()
+	childDB := WithTag(child, "db").Cmd()
+	childWeb := WithTag(child, "web").Cmd()
+
+	Send(parent, "db")("flush")
+	expectCmd(t, parentDB, "flush")
+	expectCmd(t, childDB, "flush")
+	expectNoCmd(t, childWeb)
+
+	Send(child, "db")("local")
+	expectCmd(t, childDB, "local")
+	expectNoCmd(t, parentDB)
+
+	if tags := Tags(parent); len(tags) != 1 || tags[0] != "db" {
+		t.Fatalf("Parent sees the tags of the child: %v", tags)
+	}
+}
+
+func TestChildSub(t *testing.T) {
+	parent := Tagged[string]()
+	db := Sub(parent, "db")
+	child, cancel := Child(db)
+	defer cancel()
+
+	childAll := child.Cmd()
+	childPrimary := WithTag(child, "primary").Cmd()
+	childNested := WithTag(Sub(child, "cache"), "primary").Cmd()
+
+	Send(db, "primary")("flush")
+	expectCmd(t, childAll, "flush")
+	expectCmd(t, childPrimary, "flush")
+	expectNoCmd(t, childNested)
+
+	Send(db)("all")
+	expectCmd(t, childAll, "all")
+	expectCmd(t, childPrimary, "all")
+	expectCmd(t, childNested, "all")
+}
+
+func TestNewConductorWithCtxConcurrentListeners(t *testing.T) {
+	for _, c := range []Conductor[string]{Simple[string](), Tagged[string]()} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cp := NewConductorWithCtx(c, ctx)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_ = c.Cmd()
+			}()
+			go func() {
+				defer wg.Done()
+				_ = cp.Cmd()
+			}()
+		}
+		wg.Wait()
+		cancel()
+
+		if got, expected := len(Listeners(c)[""]), len(Listeners(cp)[""]); got != expected {
+			t.Fatalf("Copies do not share listeners: %d != %d", got, expected)
+		}
+	}
+}
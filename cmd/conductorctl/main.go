@@ -0,0 +1,180 @@
+// Command conductorctl sends commands to a process exposing its conductor through
+// a control socket (see the control package).
+//
+// Usage:
+//
+//	conductorctl [-socket path] send <cmd> [--tag tag]...
+//	conductorctl [-socket path] broadcast <cmd>
+//	conductorctl [-socket path] request <cmd> [--tag tag]... [--timeout 5s]
+//	conductorctl [-socket path] list-tags
+//	conductorctl [-socket path] list-listeners
+//	conductorctl [-socket path] watch
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"git.sr.ht/~blallo/conductor/control"
+)
+
+const socketEnv = "CONDUCTOR_SOCKET"
+
+type tagList []string
+
+func (t *tagList) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagList) Set(tag string) error {
+	*t = append(*t, tag)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: %s [-socket path] <command> [args]
+
+Commands:
+  send <cmd> [--tag tag]...                 send a command to the given tags
+  broadcast <cmd>                           send a command to all the listeners
+  request <cmd> [--tag tag]... [--timeout]  send a command and wait for the replies
+  list-tags                                 list the tags with listeners
+  list-listeners                            list the listeners, grouped by tag
+  watch                                     print the commands as they are sent
+
+Flags:
+`, os.Args[0])
+	flag.PrintDefaults()
+}
+
+// parseInterleaved parses the flags of a subcommand, even when they follow the
+// positional arguments, and returns the latter.
+func parseInterleaved(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func run(client *control.Client, name string, args []string) error {
+	var tags tagList
+	var timeout time.Duration
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	switch name {
+	case control.OpSend:
+		fs.Var(&tags, "tag", "tag to send the command to (repeatable)")
+	case control.OpRequest:
+		fs.Var(&tags, "tag", "tag to send the command to (repeatable)")
+		fs.DurationVar(&timeout, "timeout", 5*time.Second, "how long to wait for the replies")
+	}
+
+	positional, err := parseInterleaved(fs, args)
+	if err != nil {
+		return err
+	}
+
+	wantArgs := 0
+	switch name {
+	case control.OpSend, control.OpBroadcast, control.OpRequest:
+		wantArgs = 1
+	}
+	if len(positional) != wantArgs {
+		return fmt.Errorf("%s: expected %d arguments, got %d", name, wantArgs, len(positional))
+	}
+
+	switch name {
+	case control.OpSend:
+		return client.Send(positional[0], tags...)
+
+	case control.OpBroadcast:
+		return client.Broadcast(positional[0])
+
+	case control.OpRequest:
+		replies, err := client.Request(positional[0], timeout, tags...)
+		for _, reply := range replies {
+			fmt.Println(string(reply))
+		}
+		return err
+
+	case control.OpListTags:
+		tags, err := client.ListTags()
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			fmt.Println(tag)
+		}
+		return nil
+
+	case control.OpListListeners:
+		listeners, err := client.ListListeners()
+		if err != nil {
+			return err
+		}
+		tags := make([]string, 0, len(listeners))
+		for tag := range listeners {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			name := tag
+			if name == "" {
+				name = "(untagged)"
+			}
+			fmt.Println(name)
+			for _, key := range listeners[tag] {
+				fmt.Println("  ", key)
+			}
+		}
+		return nil
+
+	case control.OpWatch:
+		return client.Watch(func(ev control.Event) error {
+			target := "*"
+			if len(ev.Tags) > 0 {
+				target = strings.Join(ev.Tags, ",")
+			}
+			_, err := fmt.Printf("%s\t%s\t%s\n", ev.Time.Format(time.RFC3339Nano), target, ev.Cmd)
+			return err
+		})
+
+	default:
+		return fmt.Errorf("unknown command: %s", name)
+	}
+}
+
+func main() {
+	socket := flag.String("socket", os.Getenv(socketEnv), "path of the control socket (defaults to $"+socketEnv+")")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 || *socket == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client, err := control.Dial("unix", *socket)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	if err := run(client, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		client.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,56 @@
+package conductor
+
+import (
+	"encoding/json"
+)
+
+// Codec transforms commands to and from their wire representation, whenever a
+// command has to leave the process (e.g. through a control socket).
+type Codec[T any] interface {
+	// Name identifies the codec, so that two peers can agree on it.
+	Name() string
+	// Encode serializes a command.
+	Encode(cmd T) ([]byte, error)
+	// Decode deserializes a command.
+	Decode(data []byte) (T, error)
+}
+
+type jsonCodec[T any] struct{}
+
+func (jsonCodec[T]) Name() string {
+	return "json"
+}
+
+func (jsonCodec[T]) Encode(cmd T) ([]byte, error) {
+	return json.Marshal(cmd)
+}
+
+func (jsonCodec[T]) Decode(data []byte) (cmd T, err error) {
+	err = json.Unmarshal(data, &cmd)
+	return
+}
+
+// JSONCodec creates a [Codec] that uses [encoding/json] to represent commands.
+func JSONCodec[T any]() Codec[T] {
+	return jsonCodec[T]{}
+}
+
+type stringCodec[T ~string] struct{}
+
+func (stringCodec[T]) Name() string {
+	return "string"
+}
+
+func (stringCodec[T]) Encode(cmd T) ([]byte, error) {
+	return []byte(cmd), nil
+}
+
+func (stringCodec[T]) Decode(data []byte) (T, error) {
+	return T(data), nil
+}
+
+// StringCodec creates a [Codec] for commands that are plain strings, that are sent on
+// the wire verbatim.
+func StringCodec[T ~string]() Codec[T] {
+	return stringCodec[T]{}
+}
@@ -8,8 +8,11 @@ import (
 // Sender is the return type of the [Send] function.
 type Sender[T any] func(cmd T)
 
+// TrySender is the return type of the [TrySend] function.
+type TrySender[T any] func(cmd T) error
+
 // Notifier is the return type of the [Notify] function.
-type Notifier[T any] func(cmd T, signals ...os.Signal)
+type Notifier[T any] func(cmd T, signals ...os.Signal) (stop func())
 
 // Conductor is a type useful to convey commands (represented by the generic type T)
 // across API and goroutine boundaries. It can be thought as a generalization of as
@@ -29,17 +32,57 @@ type Conductor[T any] interface {
 // Send may be used on a [Conductor] to create a function to send a command to the
 // interested listeners. It accepts a variadic amount of arguments to accommodate
 // custom behavior, depending on the specific instance of a [Conductor] it acts on.
+// The commands rejected by the interceptors attached with [InterceptSend] are logged
+// and dropped, see [TrySend] to handle the rejections.
 func Send[T any](conductor Conductor[T], args ...any) Sender[T] {
+	send := sender(conductor, args)
+	return func(cmd T) {
+		if err := send(cmd); err != nil {
+			rejected(cmd, err)
+		}
+	}
+}
+
+// TrySend is like [Send], but the created function returns the error of the
+// interceptors attached with [InterceptSend] that rejected the command, if any.
+func TrySend[T any](conductor Conductor[T], args ...any) TrySender[T] {
+	send := sender(conductor, args)
+	return func(cmd T) error {
+		return dropped(send(cmd))
+	}
+}
+
+// SendAs is like [TrySend], but the commands carry the identity of their sender, in
+// the metadata of their [Envelope] under [SenderKey], e.g. to limit their rate per
+// sender with a [Limiter].
+func SendAs[T any](conductor Conductor[T], sender string, args ...any) TrySender[T] {
+	send := envelopeSender(conductor)
+	return func(cmd T) error {
+		return dropped(send(newEnvelope(cmd, args, "").WithMeta(SenderKey, sender)))
+	}
+}
+
+// sender returns the function sending the commands through the [Conductor], to the
+// given tags.
+func sender[T any](conductor Conductor[T], args []any) func(T) error {
+	send := envelopeSender(conductor)
+	return func(cmd T) error {
+		return send(newEnvelope(cmd, args, ""))
+	}
+}
+
+// envelopeSender returns the function sending an [Envelope] through the [Conductor],
+// to its tags, or to all the listeners if it has none.
+func envelopeSender[T any](conductor Conductor[T]) func(Envelope[T]) error {
 	switch c := any(conductor).(type) {
 	case *simple[T]:
 		return c.send
 	case *tagged[T]:
-		if len(args) == 0 {
-			return c.broadcast
-		}
-		return func(cmd T) {
-			c.send(cmd, args)
-		}
+		return c.send
+	case *sub[T]:
+		return c.send
+	case *graceful[T]:
+		return envelopeSender(c.wrapped)
 	default:
 		panic("conductor not supported")
 	}
@@ -47,19 +90,43 @@ func Send[T any](conductor Conductor[T], args ...any) Sender[T] {
 
 // Notify may be used on a [Conductor] to create a function to register it to an [os.Signal],
 // in the same spirit as [os/signal.Notify]. The optional variadic args may be used to
-// configure this mechanism, depending on the specific instance of the provided [Conductor].
+// configure this mechanism, depending on the specific instance of the provided [Conductor]:
+// they are handled as by [Send], except for a [DeliveryMode], that tells how the commands
+// are delivered (synchronously by default).
+// The created function returns as soon as the signals are registered, along with a
+// function to stop listening to them, the same way [os/signal.Stop] does. Listening
+// stops anyway once the [Conductor] is done.
 func Notify[T any](conductor Conductor[T], args ...any) Notifier[T] {
-	switch c := any(conductor).(type) {
+	tags, mode := notifyOptions(args)
+	send := Send(conductor, tags...)
+
+	return func(cmd T, signals ...os.Signal) func() {
+		return listen(conductor, signals, mode, func(os.Signal) func() {
+			return func() {
+				send(cmd)
+			}
+		})
+	}
+}
+
+// Release unregisters the given listener, i.e. a channel returned by [Conductor.Cmd],
+// from the [Conductor], so that no more commands are delivered to it. It is meant to
+// be called by a listener that exits. The requests still waiting for an answer from
+// that listener receive [ErrReleased] as reply. It returns false if the listener was
+// not found.
+func Release[T any](conductor Conductor[T], lis <-chan T) bool {
+	var found bool
+	switch c := any(unwrap(conductor)).(type) {
 	case *simple[T]:
-		return c.notify
+		found = c.release(lis, listenerReleased)
 	case *tagged[T]:
-		if len(args) == 0 {
-			return c.notifyAll
-		}
-		return func(cmd T, signals ...os.Signal) {
-			c.notifyTagged(cmd, args, signals)
-		}
+		found = c.release(lis)
 	default:
 		panic("conductor not supported")
 	}
+
+	for Reply(lis, ErrReleased) {
+	}
+
+	return found
 }
@@ -0,0 +1,211 @@
+// Package config reloads a configuration file while the program runs, broadcasting the
+// new version through a [conductor.Conductor] only when its content actually changed.
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+// ErrInvalid wraps the errors of a configuration that was read, but rejected by the
+// decoder or the validator.
+var ErrInvalid = errors.New("invalid configuration")
+
+// Reload is the command sent when a new version of the configuration is applied.
+type Reload[C any] struct {
+	// Previous is the configuration that was in use until now.
+	Previous C
+	// Current is the configuration to use from now on.
+	Current C
+	// Version counts the versions applied, starting from 1 for the one loaded by
+	// [Watch].
+	Version int
+}
+
+// Rollback is the command sent when a new version of the configuration is rejected,
+// telling the listeners to stick to (or to go back to) the current one.
+type Rollback[C any] struct {
+	// Current is the configuration still in use.
+	Current C
+	// Version is the version of the configuration still in use.
+	Version int
+	// Err tells why the new version was rejected. It wraps [ErrInvalid].
+	Err error
+}
+
+// Options tells what to watch and how to turn it into commands.
+type Options[C, T any] struct {
+	// Path is the configuration file.
+	Path string
+	// Decode parses the content of the file.
+	Decode func([]byte) (C, error)
+	// Validate, if set, checks a decoded configuration before it is applied.
+	Validate func(C) error
+	// Interval, if positive, is how often the file is checked for modifications.
+	Interval time.Duration
+	// Signals, if any, make the file be checked when they are received, e.g.
+	// SIGHUP.
+	Signals []os.Signal
+	// Reload turns a [Reload] into the command to send.
+	Reload func(Reload[C]) T
+	// Rollback, if set, turns a [Rollback] into the command to send. Otherwise,
+	// nothing is sent when a new version is rejected.
+	Rollback func(Rollback[C]) T
+	// Tags are the tags the commands are sent to, as with [conductor.Send]. The
+	// commands are broadcast if there are none.
+	Tags []any
+	// OnError, if set, is called with the errors of the checks happening in the
+	// background.
+	OnError func(error)
+}
+
+// Watcher keeps a configuration file in sync with the listeners of a
+// [conductor.Conductor]. See [Watch].
+type Watcher[C, T any] struct {
+	opts    Options[C, T]
+	send    conductor.Sender[T]
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+	current C
+	version int
+	applied [sha256.Size]byte
+	// XXX: the last rejected content is remembered, so that a broken file is not
+	// reported again until it changes.
+	rejected []byte
+}
+
+// Watch loads the configuration file described by the given [Options] and watches it
+// until the returned [Watcher] is stopped or the [conductor.Conductor] is done. Each
+// time the file is checked, if its content changed and it is valid, a [Reload] command
+// is sent. If it is not valid, a [Rollback] command is sent instead. It fails if the
+// configuration cannot be loaded in the first place.
+func Watch[C, T any](c conductor.Conductor[T], opts Options[C, T]) (*Watcher[C, T], error) {
+	if opts.Decode == nil || opts.Reload == nil {
+		return nil, errors.New("config: Decode and Reload are mandatory")
+	}
+
+	w := &Watcher[C, T]{
+		opts: opts,
+		send: conductor.Send(c, opts.Tags...),
+	}
+
+	data, err := os.ReadFile(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := w.parse(data)
+	if err != nil {
+		return nil, err
+	}
+	w.current, w.version, w.applied = cfg, 1, sha256.Sum256(data)
+
+	// XXX: the checks are triggered through a private conductor, so that the file
+	// and the signals are watched with the same machinery the users have.
+	trigger, cancel := conductor.WithCancel(conductor.SimpleFromContext[struct{}](c))
+	w.cancel = cancel
+	if opts.Interval > 0 {
+		conductor.Feed(trigger, conductor.FileSource(opts.Path, opts.Interval, func(os.FileInfo) (struct{}, bool) {
+			return struct{}{}, true
+		}))
+	}
+	if len(opts.Signals) > 0 {
+		conductor.Notify(trigger)(struct{}{}, opts.Signals...)
+	}
+
+	lis := trigger.Cmd()
+	// XXX: the file may have changed between its loading and the start of the
+	// polling, so it is checked once more.
+	conductor.Send(trigger)(struct{}{})
+	go func() {
+		for {
+			select {
+			case <-trigger.Done():
+				return
+			case <-lis:
+				if err := w.Check(); err != nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+func (w *Watcher[C, T]) parse(data []byte) (C, error) {
+	cfg, err := w.opts.Decode(data)
+	if err != nil {
+		return cfg, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if w.opts.Validate != nil {
+		if err := w.opts.Validate(cfg); err != nil {
+			return cfg, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+	}
+	return cfg, nil
+}
+
+// Check reads the configuration file, and applies it if it changed, as it happens when
+// it is modified or when a signal is received. It returns an error if the file cannot
+// be read, or if it was rejected.
+func (w *Watcher[C, T]) Check() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.opts.Path)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(data) == w.applied {
+		w.rejected = nil
+		return nil
+	}
+	if w.rejected != nil && bytes.Equal(data, w.rejected) {
+		return nil
+	}
+
+	cfg, err := w.parse(data)
+	if err != nil {
+		w.rejected = data
+		if w.opts.Rollback != nil {
+			w.send(w.opts.Rollback(Rollback[C]{
+				Current: w.current,
+				Version: w.version,
+				Err:     err,
+			}))
+		}
+		return err
+	}
+
+	previous := w.current
+	w.current, w.applied, w.rejected = cfg, sha256.Sum256(data), nil
+	w.version++
+	w.send(w.opts.Reload(Reload[C]{
+		Previous: previous,
+		Current:  cfg,
+		Version:  w.version,
+	}))
+
+	return nil
+}
+
+// Current returns the configuration in use, along with its version.
+func (w *Watcher[C, T]) Current() (C, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current, w.version
+}
+
+// Stop stops watching the configuration file.
+func (w *Watcher[C, T]) Stop() {
+	w.cancel()
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+const timeout = 200 * time.Millisecond
+
+type settings struct {
+	Workers int
+}
+
+func decode(data []byte) (settings, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return settings{Workers: n}, err
+}
+
+func validate(s settings) error {
+	if s.Workers <= 0 {
+		return errors.New("workers must be positive")
+	}
+	return nil
+}
+
+func options(path string) Options[settings, any] {
+	return Options[settings, any]{
+		Path:     path,
+		Decode:   decode,
+		Validate: validate,
+		Reload:   func(r Reload[settings]) any { return r },
+		Rollback: func(r Rollback[settings]) any { return r },
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(t *testing.T, lis <-chan any) any {
+	t.Helper()
+	select {
+	case cmd := <-lis:
+		return cmd
+	case <-time.After(timeout):
+		t.Fatal("No command received")
+		return nil
+	}
+}
+
+func TestWatchPolling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workers")
+	write(t, path, "1")
+
+	c := conductor.Simple[any]()
+	lis := c.Cmd()
+
+	opts := options(path)
+	opts.Interval = 5 * time.Millisecond
+	w, err := Watch(c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if cfg, version := w.Current(); cfg.Workers != 1 || version != 1 {
+		t.Fatalf("Unexpected initial configuration: %v %d", cfg, version)
+	}
+
+	write(t, path, "4")
+	reload, ok := receive(t, lis).(Reload[settings])
+	if !ok || reload.Previous.Workers != 1 || reload.Current.Workers != 4 || reload.Version != 2 {
+		t.Fatalf("Unexpected reload: %+v", reload)
+	}
+
+	write(t, path, "0")
+	rollback, ok := receive(t, lis).(Rollback[settings])
+	if !ok || rollback.Current.Workers != 4 || !errors.Is(rollback.Err, ErrInvalid) {
+		t.Fatalf("Unexpected rollback: %+v", rollback)
+	}
+
+	if cfg, version := w.Current(); cfg.Workers != 4 || version != 2 {
+		t.Fatalf("Rejected configuration applied: %v %d", cfg, version)
+	}
+}
+
+func TestWatchSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workers")
+	write(t, path, "1")
+
+	c := conductor.Tagged[any]()
+	lis := conductor.WithTag(c, "pool").Cmd()
+
+	opts := options(path)
+	opts.Signals = []os.Signal{syscall.SIGWINCH}
+	opts.Tags = []any{"pool"}
+	w, err := Watch(c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	raise := func() {
+		t.Helper()
+		if err := syscall.Kill(os.Getpid(), syscall.SIGWINCH); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Same content, with a different formatting that does not matter to the
+	// decoder: it is still a change of the content.
+	write(t, path, "2\n")
+	raise()
+	if reload, ok := receive(t, lis).(Reload[settings]); !ok || reload.Current.Workers != 2 {
+		t.Fatalf("Unexpected reload: %+v", reload)
+	}
+
+	// Nothing changed, so nothing is sent.
+	raise()
+	select {
+	case cmd := <-lis:
+		t.Fatalf("Unexpected command: %+v", cmd)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatchCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workers")
+	write(t, path, "1")
+
+	c := conductor.Simple[any]()
+	lis := c.Cmd()
+
+	w, err := Watch(c, options(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	write(t, path, "nope")
+	if err := w.Check(); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := receive(t, lis).(Rollback[settings]); !ok {
+		t.Fatal("Rollback not sent")
+	}
+
+	// The same broken content is not reported twice.
+	if err := w.Check(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(lis) != 0 {
+		t.Fatal("Rollback sent twice")
+	}
+
+	if _, err := Watch(c, options(filepath.Join(t.TempDir(), "missing"))); err == nil {
+		t.Fatal("Missing configuration loaded")
+	}
+}
@@ -29,28 +29,107 @@ func WithTimeout[T any](conductor Conductor[T], interval time.Duration) (Conduct
 	return NewConductorWithCtx(conductor, ctx), cancel
 }
 
+// WithCancelCause mimics what [context.WithCancelCause] does, but for a [Conductor]. It
+// behaves like [WithCancel], but the returned function records a cause, that is handed
+// to the [CausePolicy] attached to the conductor, and that may be retrieved with
+// [context.Cause].
+func WithCancelCause[T any](conductor Conductor[T]) (Conductor[T], context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(conductor)
+	return NewConductorWithCtx(conductor, ctx), cancel
+}
+
+// WithDeadlineCause mimics what [context.WithDeadlineCause] does, but for a [Conductor].
+// It behaves like [WithDeadline], but when the deadline is exceeded the given cause is
+// recorded.
+func WithDeadlineCause[T any](conductor Conductor[T], deadline time.Time, cause error) (Conductor[T], context.CancelFunc) {
+	ctx, cancel := context.WithDeadlineCause(conductor, deadline, cause)
+	return NewConductorWithCtx(conductor, ctx), cancel
+}
+
+// WithTimeoutCause mimics what [context.WithTimeoutCause] does, but for a [Conductor].
+// It behaves like [WithTimeout], but when the timeout expires the given cause is
+// recorded.
+func WithTimeoutCause[T any](conductor Conductor[T], interval time.Duration, cause error) (Conductor[T], context.CancelFunc) {
+	ctx, cancel := context.WithTimeoutCause(conductor, interval, cause)
+	return NewConductorWithCtx(conductor, ctx), cancel
+}
+
+// WithValue mimics what [context.WithValue] does, but for a [Conductor]. It returns a
+// *copy* of the given conductor, that carries the given value associated to the key.
+func WithValue[T any](conductor Conductor[T], key, val any) Conductor[T] {
+	return NewConductorWithCtx(conductor, context.WithValue(conductor, key, val))
+}
+
+// WithoutCancel mimics what [context.WithoutCancel] does, but for a [Conductor]. It
+// returns a *copy* of the given conductor, that is not canceled when the given one is.
+// The listeners are shared nonetheless, so that they keep receiving the commands sent
+// through the copy.
+func WithoutCancel[T any](conductor Conductor[T]) Conductor[T] {
+	return NewConductorWithCtx(conductor, context.WithoutCancel(conductor))
+}
+
+// AfterFunc mimics what [context.AfterFunc] does, but for a [Conductor]: it calls f in
+// its own goroutine once the conductor is done and the commands decided by its policies
+// have been fired. Calling the returned stop function prevents f from being run, and
+// reports whether it did so.
+func AfterFunc[T any](conductor Conductor[T], f func()) (stop func() bool) {
+	return context.AfterFunc(conductor, func() {
+		for _, fired := range hubOf(conductor).firing() {
+			<-fired
+		}
+		f()
+	})
+}
+
+type contextKey[T any] struct{}
+
+// NewContext returns a copy of the given [context.Context] carrying the given
+// [Conductor], to be retrieved with [FromContext].
+func NewContext[T any](ctx context.Context, conductor Conductor[T]) context.Context {
+	return context.WithValue(ctx, contextKey[T]{}, conductor)
+}
+
+// FromContext returns the [Conductor] carried by the given [context.Context], if any
+// was stored with [NewContext]. A context that is itself a [Conductor] is returned as
+// is.
+func FromContext[T any](ctx context.Context) (Conductor[T], bool) {
+	if c, ok := ctx.Value(contextKey[T]{}).(Conductor[T]); ok {
+		return c, true
+	}
+	c, ok := ctx.(Conductor[T])
+	return c, ok
+}
+
 // NewConductorWithCtx creates a new conductor that hinerits the features of the given
-// one, but replaces the inner context.Context.
+// one, but replaces the inner context.Context. The listeners are shared with the given
+// conductor: use [Child] to get a conductor with its own listeners.
 // NOTE: the conductor must be non-nil, or the function will panic.
 func NewConductorWithCtx[T any](conductor Conductor[T], ctx context.Context) Conductor[T] {
 	switch c := any(conductor).(type) {
 	case *simple[T]:
+		// XXX: the copy shares the lock along with the listeners, so that they can
+		// be registered concurrently on both.
 		return &simple[T]{
 			listeners: c.listeners,
+			mu:        c.mu,
 			ctx:       ctx,
+			logFile:   c.logFile,
+			hub:       c.hub,
+			tag:       c.tag,
 		}
 	case *tagged[T]:
-		listeners := make(map[any]*simple[T])
-		for k, v := range c.tagged {
-			newV := &simple[T]{
-				listeners: v.listeners,
-				ctx:       ctx,
-			}
-			listeners[k] = newV
-		}
 		return &tagged[T]{
-			tagged: listeners,
+			tagged: c.tagged,
+			mu:     c.mu,
 			ctx:    ctx,
+			hub:    c.hub,
+		}
+	case *graceful[T]:
+		return Graceful(NewConductorWithCtx(c.wrapped, ctx), c.timeout)
+	case *sub[T]:
+		return &sub[T]{
+			wrapped: NewConductorWithCtx[T](c.wrapped, ctx).(*tagged[T]),
+			prefix:  c.prefix,
 		}
 	default:
 		panic("unsupported conductor")
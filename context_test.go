@@ -1,6 +1,8 @@
 package conductor
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -48,3 +50,120 @@ func TestWithTimeout(t *testing.T) {
 		t.Fatal("Timeout")
 	}
 }
+
+func TestWithCancelCause(t *testing.T) {
+	cause := errors.New("restart")
+	c, cancel := WithCancelCause(Simple[string]())
+
+	go cancel(cause)
+
+	select {
+	case <-c.Done():
+		if !errors.Is(context.Cause(c), cause) {
+			t.Fatalf("Unexpected cause: %v", context.Cause(c))
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+}
+
+func TestWithTimeoutCause(t *testing.T) {
+	const delta = 25 * time.Millisecond
+	cause := errors.New("too slow")
+
+	c, cancel := WithTimeoutCause(Simple[string](), delta, cause)
+	defer cancel()
+
+	select {
+	case <-c.Done():
+		if !errors.Is(c.Err(), context.DeadlineExceeded) || !errors.Is(context.Cause(c), cause) {
+			t.Fatalf("Unexpected error and cause: %v, %v", c.Err(), context.Cause(c))
+		}
+	case <-time.After(2 * delta):
+		t.Fatal("Timeout")
+	}
+}
+
+func TestWithValue(t *testing.T) {
+	type key struct{}
+
+	c := Simple[string]()
+	lis := c.Cmd()
+	v := WithValue(c, key{}, "value")
+
+	if got := v.Value(key{}); got != "value" {
+		t.Fatalf("Unexpected value: %v", got)
+	}
+	if got := c.Value(key{}); got != nil {
+		t.Fatalf("Value leaked to the parent: %v", got)
+	}
+
+	Send(v)("ciao")
+	expectCmd(t, lis, "ciao")
+}
+
+func TestWithoutCancel(t *testing.T) {
+	c, cancel := WithCancel(Tagged[string]())
+	detached := WithoutCancel(c)
+
+	cancel()
+
+	select {
+	case <-detached.Done():
+		t.Fatal("Detached conductor canceled")
+	case <-time.After(successTimeout):
+	}
+
+	lis := WithTag(c, "red").Cmd()
+	Send(detached, "red")("ciao")
+	expectCmd(t, lis, "ciao")
+}
+
+func TestAfterFunc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := SimpleFromContext[string](ctx).
+		WithContextPolicy(SequencePolicy(
+			Step[string]{Cmd: "pause"},
+			Step[string]{Cmd: "stop", Delay: 10 * time.Millisecond},
+		))
+	lis := c.Cmd()
+
+	called := make(chan int)
+	AfterFunc(c, func() {
+		called <- len(lis)
+	})
+
+	cancel()
+
+	select {
+	case n := <-called:
+		if n != 2 {
+			t.Fatalf("Called before the policy fired: %d commands", n)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Not called")
+	}
+
+	stop := AfterFunc(Simple[string](), func() {})
+	if !stop() {
+		t.Fatal("Not stopped")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	c := Simple[string]()
+
+	ctx := NewContext(context.Background(), c)
+	if got, ok := FromContext[string](ctx); !ok || got != c {
+		t.Fatal("Conductor not found")
+	}
+	if _, ok := FromContext[int](ctx); ok {
+		t.Fatal("Conductor of another type found")
+	}
+	if got, ok := FromContext[string](c); !ok || got != c {
+		t.Fatal("Conductor not recognized")
+	}
+	if _, ok := FromContext[string](context.Background()); ok {
+		t.Fatal("Conductor found in an empty context")
+	}
+}
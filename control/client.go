@@ -0,0 +1,123 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// Client talks to a control server started with [Serve].
+type Client struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	enc     *json.Encoder
+}
+
+// Dial connects to a control server listening at the given address.
+func Dial(network, address string) (*Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		conn:    conn,
+		scanner: bufio.NewScanner(conn),
+		enc:     json.NewEncoder(conn),
+	}, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Send sends the command to the listeners of the given tags. With no tags, the
+// command is broadcast. It returns the error of the interceptor of the server that
+// rejected the command, if any.
+func (c *Client) Send(cmd string, tags ...string) error {
+	_, err := c.roundTrip(Message{Op: OpSend, Cmd: cmd, Tags: tags})
+	return err
+}
+
+// Broadcast sends the command to all the listeners. It fails like [Client.Send] does.
+func (c *Client) Broadcast(cmd string) error {
+	_, err := c.roundTrip(Message{Op: OpBroadcast, Cmd: cmd})
+	return err
+}
+
+// Request sends the command as [Client.Send] does, and waits at most timeout for the
+// listeners to reply. On timeout, the replies received so far are returned together
+// with the error.
+func (c *Client) Request(cmd string, timeout time.Duration, tags ...string) ([]json.RawMessage, error) {
+	resp, err := c.roundTrip(Message{Op: OpRequest, Cmd: cmd, Tags: tags, Timeout: timeout})
+	return resp.Replies, err
+}
+
+// ListTags returns the tags known to the server.
+func (c *Client) ListTags() ([]string, error) {
+	resp, err := c.roundTrip(Message{Op: OpListTags})
+	return resp.Tags, err
+}
+
+// ListListeners returns the listeners known to the server, grouped by tag.
+func (c *Client) ListListeners() (map[string][]string, error) {
+	resp, err := c.roundTrip(Message{Op: OpListListeners})
+	return resp.Listeners, err
+}
+
+// Watch calls fn for every command sent through the conductor on the server side,
+// until fn returns an error, the connection is closed or the server goes away. After
+// a call to Watch, the [Client] cannot be used anymore.
+func (c *Client) Watch(fn func(Event) error) error {
+	if _, err := c.roundTrip(Message{Op: OpWatch}); err != nil {
+		return err
+	}
+
+	for {
+		resp, err := c.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if resp.Event == nil {
+			continue
+		}
+		if err := fn(*resp.Event); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) roundTrip(msg Message) (Response, error) {
+	if err := c.enc.Encode(msg); err != nil {
+		return Response{}, err
+	}
+
+	resp, err := c.read()
+	if err != nil {
+		return resp, err
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Error)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) read() (resp Response, err error) {
+	if !c.scanner.Scan() {
+		if err = c.scanner.Err(); err == nil {
+			err = io.EOF
+		}
+		return
+	}
+
+	err = json.Unmarshal(c.scanner.Bytes(), &resp)
+	return
+}
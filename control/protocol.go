@@ -0,0 +1,42 @@
+package control
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Operations understood by the control server.
+const (
+	OpSend          = "send"
+	OpBroadcast     = "broadcast"
+	OpListTags      = "list-tags"
+	OpListListeners = "list-listeners"
+	OpWatch         = "watch"
+	OpRequest       = "request"
+)
+
+// Message is what a client writes on the control socket, one JSON object per line.
+type Message struct {
+	Op      string        `json:"op"`
+	Cmd     string        `json:"cmd,omitempty"`
+	Tags    []string      `json:"tags,omitempty"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// Response is what the server answers to a [Message], one JSON object per line. A
+// watch is answered by a stream of responses, each one carrying an [Event].
+type Response struct {
+	OK        bool                `json:"ok"`
+	Error     string              `json:"error,omitempty"`
+	Tags      []string            `json:"tags,omitempty"`
+	Listeners map[string][]string `json:"listeners,omitempty"`
+	Replies   []json.RawMessage   `json:"replies,omitempty"`
+	Event     *Event              `json:"event,omitempty"`
+}
+
+// Event describes a command that has been sent through the watched conductor.
+type Event struct {
+	Cmd  string    `json:"cmd"`
+	Tags []string  `json:"tags,omitempty"`
+	Time time.Time `json:"time"`
+}
@@ -0,0 +1,234 @@
+// Package control exposes a [conductor.Conductor] on a socket, so that commands can
+// be sent to a running process from the outside, e.g. using conductorctl.
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+const defaultRequestTimeout = 5 * time.Second
+
+type server[T any] struct {
+	c     conductor.Conductor[T]
+	codec conductor.Codec[T]
+}
+
+// Serve accepts connections on the given [net.Listener] and executes the messages
+// received on them against the given [conductor.Conductor], using the [conductor.Codec]
+// to translate commands. It blocks until the conductor is done, closing the listener,
+// or until accepting a connection fails.
+func Serve[T any](c conductor.Conductor[T], l net.Listener, codec conductor.Codec[T]) error {
+	s := &server[T]{
+		c:     c,
+		codec: codec,
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-c.Done():
+			l.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if c.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// ListenAndServe creates a unix socket at the given path and calls [Serve] on it. A
+// stale socket file left at the same path is removed first.
+func ListenAndServe[T any](c conductor.Conductor[T], path string, codec conductor.Codec[T]) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	return Serve(c, l, codec)
+}
+
+func (s *server[T]) handle(conn net.Conn) {
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.c.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			enc.Encode(failure(err))
+			continue
+		}
+
+		if msg.Op == OpWatch {
+			s.watch(conn, enc)
+			return
+		}
+
+		if err := enc.Encode(s.exec(msg)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *server[T]) exec(msg Message) Response {
+	switch msg.Op {
+	case OpSend, OpBroadcast:
+		cmd, err := s.codec.Decode([]byte(msg.Cmd))
+		if err != nil {
+			return failure(err)
+		}
+		tags := toAny(msg.Tags)
+		if msg.Op == OpBroadcast {
+			tags = nil
+		}
+		if err := conductor.TrySend(s.c, tags...)(cmd); err != nil {
+			return failure(err)
+		}
+		return Response{OK: true}
+
+	case OpRequest:
+		cmd, err := s.codec.Decode([]byte(msg.Cmd))
+		if err != nil {
+			return failure(err)
+		}
+		timeout := msg.Timeout
+		if timeout <= 0 {
+			timeout = defaultRequestTimeout
+		}
+		ctx, cancel := context.WithTimeout(s.c, timeout)
+		defer cancel()
+
+		replies, err := conductor.Request(s.c, toAny(msg.Tags)...)(ctx, cmd)
+		resp := Response{OK: err == nil}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		for _, reply := range replies {
+			resp.Replies = append(resp.Replies, marshalReply(reply))
+		}
+		return resp
+
+	case OpListTags:
+		return Response{
+			OK:   true,
+			Tags: conductor.Tags(s.c),
+		}
+
+	case OpListListeners:
+		return Response{
+			OK:        true,
+			Listeners: conductor.Listeners(s.c),
+		}
+
+	default:
+		return failure(fmt.Errorf("unknown operation: %q", msg.Op))
+	}
+}
+
+func (s *server[T]) watch(conn net.Conn, enc *json.Encoder) {
+	envelopes, stop := conductor.Watch(s.c)
+	defer stop()
+
+	if err := enc.Encode(Response{OK: true}); err != nil {
+		return
+	}
+
+	// XXX: the client is not expected to write anything more, so a read returns
+	// only when the connection gets closed.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var buf [1]byte
+		conn.Read(buf[:])
+	}()
+
+	for {
+		select {
+		case env := <-envelopes:
+			data, err := s.codec.Encode(env.Cmd)
+			if err != nil {
+				data = []byte(fmt.Sprint(env.Cmd))
+			}
+			event := &Event{
+				Cmd:  string(data),
+				Tags: toString(env.Tags),
+				Time: env.Time,
+			}
+			if err := enc.Encode(Response{OK: true, Event: event}); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-s.c.Done():
+			return
+		}
+	}
+}
+
+func failure(err error) Response {
+	return Response{
+		Error: err.Error(),
+	}
+}
+
+func marshalReply(reply any) json.RawMessage {
+	if err, ok := reply.(error); ok {
+		reply = err.Error()
+	}
+	data, err := json.Marshal(reply)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(reply))
+	}
+	return data
+}
+
+func toAny(tags []string) []any {
+	args := make([]any, len(tags))
+	for i, tag := range tags {
+		args[i] = tag
+	}
+	return args
+}
+
+func toString(tags []any) []string {
+	if tags == nil {
+		return nil
+	}
+	strs := make([]string, len(tags))
+	for i, tag := range tags {
+		strs[i] = fmt.Sprint(tag)
+	}
+	return strs
+}
@@ -0,0 +1,214 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+const failureTimeout = 100 * time.Millisecond
+
+func setup(t *testing.T) (conductor.Conductor[string], *Client) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c := conductor.TaggedFromContext[string](ctx)
+
+	path := filepath.Join(t.TempDir(), "conductor.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go Serve(c, l, conductor.StringCodec[string]())
+
+	client, err := Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return c, client
+}
+
+func TestSend(t *testing.T) {
+	c, client := setup(t)
+
+	red := conductor.WithTag(c, "red").Cmd()
+	blue := conductor.WithTag(c, "blue").Cmd()
+
+	if err := client.Send("pause", "red"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case cmd := <-red:
+		if cmd != "pause" {
+			t.Fatalf("Unexpected cmd: %s", cmd)
+		}
+	case cmd := <-blue:
+		t.Fatalf("blue received: %s", cmd)
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+}
+
+func TestSendRejected(t *testing.T) {
+	c, client := setup(t)
+
+	red := conductor.WithTag(c, "red").Cmd()
+	rejected := errors.New("paused")
+	conductor.InterceptSend(c, func(env conductor.Envelope[string], next func(conductor.Envelope[string]) error) error {
+		return rejected
+	})
+
+	if err := client.Send("pause", "red"); err == nil || err.Error() != rejected.Error() {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case cmd := <-red:
+		t.Fatalf("Rejected cmd received: %s", cmd)
+	case <-time.After(failureTimeout):
+	}
+}
+
+func TestBroadcastRejected(t *testing.T) {
+	c, client := setup(t)
+
+	lis := c.Cmd()
+	rejected := errors.New("paused")
+	conductor.InterceptSend(c, func(env conductor.Envelope[string], next func(conductor.Envelope[string]) error) error {
+		return rejected
+	})
+
+	if err := client.Broadcast("pause"); err == nil || err.Error() != rejected.Error() {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case cmd := <-lis:
+		t.Fatalf("Rejected cmd received: %s", cmd)
+	case <-time.After(failureTimeout):
+	}
+}
+
+func TestList(t *testing.T) {
+	c, client := setup(t)
+
+	conductor.WithTag(c, "red").Cmd()
+
+	tags, err := client.ListTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0] != "red" {
+		t.Fatalf("Unexpected tags: %v", tags)
+	}
+
+	listeners, err := client.ListListeners()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners["red"]) != 1 {
+		t.Fatalf("Unexpected listeners: %v", listeners)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	c, client := setup(t)
+
+	lis := conductor.WithTag(c, "red").Cmd()
+	go func() {
+		cmd := <-lis
+		conductor.Reply(lis, "done "+cmd)
+	}()
+
+	replies, err := client.Request("flush", failureTimeout, "red")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reply string
+	if len(replies) != 1 {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+	if err := json.Unmarshal(replies[0], &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply != "done flush" {
+		t.Fatalf("Unexpected reply: %s", reply)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c, client := setup(t)
+
+	stop := errors.New("stop")
+	events := make(chan Event, 1)
+	watching := make(chan error, 1)
+	go func() {
+		watching <- client.Watch(func(ev Event) error {
+			events <- ev
+			return stop
+		})
+	}()
+
+	// XXX: give the server the time to register the watcher.
+	time.Sleep(20 * time.Millisecond)
+	conductor.Send(c, "red")("pause")
+
+	select {
+	case ev := <-events:
+		if ev.Cmd != "pause" || len(ev.Tags) != 1 || ev.Tags[0] != "red" {
+			t.Fatalf("Unexpected event: %+v", ev)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+
+	if err := <-watching; !errors.Is(err, stop) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestConnectionsDoNotLeak(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := conductor.TaggedFromContext[string](ctx)
+
+	path := filepath.Join(t.TempDir(), "conductor.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Serve runs in its own goroutine, along with the one closing the listener.
+	before := runtime.NumGoroutine() + 2
+	go Serve(c, l, conductor.StringCodec[string]())
+
+	for i := 0; i < 20; i++ {
+		client, err := Dial("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Send("ping"); err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("Goroutines leaked: %d, before %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
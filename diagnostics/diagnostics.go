@@ -0,0 +1,210 @@
+// Package diagnostics captures profiles and dumps of a running program when it receives
+// the commands of a [conductor.Conductor], writing them to a directory. Combined with
+// [conductor.Notify], it allows profiling in production without exposing an HTTP port.
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+const (
+	defaultCPUDuration   = 30 * time.Second
+	defaultTraceDuration = 5 * time.Second
+	defaultMaxDuration   = 5 * time.Minute
+	timeFormat           = "20060102T150405.000000000"
+)
+
+// Kind is the kind of a [Capture].
+type Kind int
+
+const (
+	// CPU is a CPU profile, see [pprof.StartCPUProfile].
+	CPU Kind = iota
+	// Heap is a heap profile.
+	Heap
+	// Goroutines is a dump of the stacks of all the goroutines.
+	Goroutines
+	// Execution is an execution trace, see [trace.Start].
+	Execution
+)
+
+// Capture tells which diagnostics to capture, carried by a command.
+type Capture struct {
+	Kind Kind
+	// Duration is how long a CPU profile or an execution trace lasts, at most
+	// [Options.MaxDuration].
+	Duration time.Duration
+}
+
+// CPUProfile captures a CPU profile lasting the given duration, or 30s if zero.
+func CPUProfile(d time.Duration) Capture {
+	return Capture{Kind: CPU, Duration: d}
+}
+
+// HeapProfile captures a heap profile.
+func HeapProfile() Capture {
+	return Capture{Kind: Heap}
+}
+
+// GoroutineDump captures the stacks of all the goroutines.
+func GoroutineDump() Capture {
+	return Capture{Kind: Goroutines}
+}
+
+// Trace captures an execution trace lasting the given duration, or 5s if zero.
+func Trace(d time.Duration) Capture {
+	return Capture{Kind: Execution, Duration: d}
+}
+
+// Options tune a [Listener].
+type Options[T any] struct {
+	// Dir is the directory where to write the captures. Defaults to [os.TempDir].
+	Dir string
+	// Decide tells which [Capture] a command carries, if any. Defaults to use the
+	// commands that are a [Capture] as is.
+	Decide func(T) (Capture, bool)
+	// MaxDuration is the longest a CPU profile or an execution trace lasts, whatever
+	// the [Capture] asks. Defaults to 5 minutes.
+	MaxDuration time.Duration
+}
+
+// Listener is a listener of a [conductor.Conductor] capturing diagnostics. See [Listen].
+type Listener struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Listen listens to the commands of the given [conductor.Conductor], capturing the
+// diagnostics described by the [Capture] they carry, until the returned [Listener] is
+// stopped or the [conductor.Conductor] is done. The captures happen one at a time, and
+// the requests made with [conductor.Request] are answered with the path of the file
+// written, or with the error that prevented writing it. A CPU profile or an execution
+// trace in progress is cut short, and written, when the [Listener] is stopped or the
+// [conductor.Conductor] is done.
+func Listen[T any](c conductor.Conductor[T], opts Options[T]) *Listener {
+	decide := opts.Decide
+	if decide == nil {
+		decide = func(cmd T) (Capture, bool) {
+			capture, ok := any(cmd).(Capture)
+			return capture, ok
+		}
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	limit := opts.MaxDuration
+	if limit <= 0 {
+		limit = defaultMaxDuration
+	}
+
+	l := &Listener{}
+	l.ctx, l.cancel = context.WithCancel(c)
+
+	conductor.Spawn(c, func(lis <-chan T) {
+		defer conductor.Release(c, lis)
+
+		for {
+			select {
+			case <-l.ctx.Done():
+				return
+			case cmd := <-lis:
+				capture, ok := decide(cmd)
+				if !ok {
+					conductor.Ack(lis)
+					continue
+				}
+				path, err := capture.write(l.ctx, dir, limit)
+				if err != nil {
+					conductor.Reply(lis, err)
+					continue
+				}
+				conductor.Reply(lis, path)
+			}
+		}
+	})
+
+	return l
+}
+
+// Stop stops listening to the commands. A capture in progress is cut short.
+func (l *Listener) Stop() {
+	l.cancel()
+}
+
+func (c Capture) write(ctx context.Context, dir string, limit time.Duration) (string, error) {
+	var name string
+	switch c.Kind {
+	case CPU:
+		name = "cpu-%s.pprof"
+	case Heap:
+		name = "heap-%s.pprof"
+	case Goroutines:
+		name = "goroutines-%s.txt"
+	case Execution:
+		name = "trace-%s.out"
+	default:
+		return "", fmt.Errorf("unknown capture kind %d", c.Kind)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf(name, time.Now().Format(timeFormat)))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	switch c.Kind {
+	case CPU:
+		err = capture(ctx, f, min(duration(c.Duration, defaultCPUDuration), limit), pprof.StartCPUProfile, pprof.StopCPUProfile)
+	case Heap:
+		err = pprof.Lookup("heap").WriteTo(f, 0)
+	case Goroutines:
+		err = pprof.Lookup("goroutine").WriteTo(f, 2)
+	case Execution:
+		err = capture(ctx, f, min(duration(c.Duration, defaultTraceDuration), limit), trace.Start, trace.Stop)
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	return path, nil
+}
+
+// duration returns the given duration, or the fallback one if zero.
+func duration(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// capture runs a capture lasting the given duration, or until the context is done.
+func capture(ctx context.Context, f *os.File, d time.Duration, start func(w io.Writer) error, stop func()) error {
+	if err := start(f); err != nil {
+		return err
+	}
+	defer stop()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return nil
+}
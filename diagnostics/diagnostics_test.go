@@ -0,0 +1,139 @@
+package diagnostics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+func TestListen(t *testing.T) {
+	dir := t.TempDir()
+	c := conductor.Simple[Capture]()
+
+	l := Listen(c, Options[Capture]{Dir: dir})
+	defer l.Stop()
+
+	for _, tc := range []struct {
+		capture Capture
+		prefix  string
+	}{
+		{CPUProfile(20 * time.Millisecond), "cpu-"},
+		{HeapProfile(), "heap-"},
+		{GoroutineDump(), "goroutines-"},
+		{Trace(20 * time.Millisecond), "trace-"},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		replies, err := conductor.Request(c)(ctx, tc.capture)
+		cancel()
+		if err != nil || len(replies) != 1 {
+			t.Fatalf("Unexpected replies: %v %v", replies, err)
+		}
+
+		path, ok := replies[0].(string)
+		if !ok {
+			t.Fatalf("Capture failed: %v", replies[0])
+		}
+		if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), tc.prefix) {
+			t.Fatalf("Unexpected path: %s", path)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() == 0 {
+			t.Fatalf("Empty capture: %s", path)
+		}
+	}
+
+	data, err := os.ReadFile(findOne(t, dir, "goroutines-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "goroutine ") {
+		t.Fatal("Not a goroutine dump")
+	}
+}
+
+func TestListenError(t *testing.T) {
+	c := conductor.Simple[string]()
+
+	l := Listen(c, Options[string]{
+		Dir: filepath.Join(t.TempDir(), "missing"),
+		Decide: func(cmd string) (Capture, bool) {
+			return HeapProfile(), cmd == "heap"
+		},
+	})
+	defer l.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	replies, err := conductor.Request(c)(ctx, "heap")
+	if err != nil || len(replies) != 1 {
+		t.Fatalf("Unexpected replies: %v %v", replies, err)
+	}
+	if _, ok := replies[0].(error); !ok {
+		t.Fatalf("Unexpected reply: %v", replies[0])
+	}
+
+	replies, err = conductor.Request(c)(ctx, "other")
+	if err != nil || len(replies) != 1 || replies[0] != nil {
+		t.Fatalf("Unexpected replies: %v %v", replies, err)
+	}
+}
+
+func TestListenBounded(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		limit time.Duration
+		stop  func(*Listener, context.CancelFunc)
+	}{
+		{"stopped", time.Hour, func(l *Listener, _ context.CancelFunc) { l.Stop() }},
+		{"done", time.Hour, func(_ *Listener, cancel context.CancelFunc) { cancel() }},
+		{"capped", 20 * time.Millisecond, func(*Listener, context.CancelFunc) {}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c := conductor.Simple[Capture]().WithContext(ctx)
+
+			l := Listen(c, Options[Capture]{Dir: t.TempDir(), MaxDuration: tc.limit})
+			defer l.Stop()
+
+			replies := make(chan []any, 1)
+			go func() {
+				r, _ := conductor.Request(c)(context.Background(), Trace(time.Hour))
+				replies <- r
+			}()
+			time.Sleep(50 * time.Millisecond)
+			tc.stop(l, cancel)
+
+			select {
+			case r := <-replies:
+				if len(r) != 1 {
+					t.Fatalf("Unexpected replies: %v", r)
+				}
+				if _, ok := r[0].(string); !ok {
+					t.Fatalf("Capture failed: %v", r[0])
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Capture not cut short")
+			}
+		})
+	}
+}
+
+func findOne(t *testing.T, dir, pattern string) string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("Unexpected matches: %v %v", matches, err)
+	}
+	return matches[0]
+}
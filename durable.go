@@ -0,0 +1,485 @@
+package conductor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"git.sr.ht/~blallo/conductor/internal/seen"
+)
+
+const (
+	defaultRedeliverAfter = 30 * time.Second
+	durableSeenSize       = 4096
+	durableQueueFile      = "queue"
+	durableCompactSize    = 1 << 20
+)
+
+// ErrNotPending is returned when acknowledging a command that is not waiting for it.
+var ErrNotPending = errors.New("command not pending")
+
+type durableRecord struct {
+	Op     string            `json:"op"`
+	ID     string            `json:"id"`
+	Time   time.Time         `json:"time,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+	Origin string            `json:"origin,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty"`
+	Cmd    []byte            `json:"cmd,omitempty"`
+}
+
+const (
+	durablePush = "push"
+	durableAck  = "ack"
+)
+
+// DurableListener is a named listener whose pending commands are persisted on disk,
+// and redelivered until acknowledged with [DurableListener.Ack], even across restarts.
+// Commands are delivered one at a time, in the order they were sent, and those whose
+// ID was already received are discarded. It coexists with the listeners created with
+// [Conductor.Cmd].
+type DurableListener[T any] struct {
+	conductor      Conductor[T]
+	codec          Codec[T]
+	tag            string
+	sees           func(Envelope[T]) bool
+	redeliverAfter time.Duration
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	synced   int64
+	path     string
+	pending  []Envelope[T]
+	inflight string
+	seen     *seen.Set
+	unhook   func()
+	detach   func()
+	wake     chan struct{}
+	out      chan Envelope[T]
+	closed   chan struct{}
+	once     sync.Once
+
+	// XXX: the commands written meanwhile share a single fsync, see sync.
+	syncMu sync.Mutex
+}
+
+// Durable creates a [DurableListener] on the given [Conductor], persisting its queue in
+// a directory named after the listener, inside dir. If the conductor has been loaded
+// with [WithTag], the listener receives the commands sent to that tag and the broadcast
+// ones; on a sub-conductor (see [Sub]), the ones sent to its namespace and the broadcast
+// ones; otherwise it receives every command. Commands not acknowledged within
+// redeliverAfter are delivered again; a zero value means 30 seconds. The commands left
+// pending by a previous run are delivered first.
+//
+// The commands sent, as done by [Send], [TrySend] and [Request], are persisted before
+// being delivered, once the interceptors attached with [InterceptSend] let them
+// through: if that fails, the command is delivered to no listener, and [TrySend] and
+// [Request] return the error. The other commands, fired by a [Policy] or delivered with
+// [Deliver], are persisted while being delivered, and only logged if that fails.
+func Durable[T any](conductor Conductor[T], dir, name string, codec Codec[T], redeliverAfter time.Duration) (*DurableListener[T], error) {
+	if redeliverAfter <= 0 {
+		redeliverAfter = defaultRedeliverAfter
+	}
+
+	var tag string
+	if l, ok := any(conductor).(*loaded[T]); ok {
+		tag = l.tag
+	}
+
+	d := &DurableListener[T]{
+		conductor:      conductor,
+		codec:          codec,
+		tag:            tag,
+		sees:           sees(conductor),
+		redeliverAfter: redeliverAfter,
+		path:           filepath.Join(dir, name, durableQueueFile),
+		seen:           seen.New(durableSeenSize),
+		wake:           make(chan struct{}, 1),
+		out:            make(chan Envelope[T]),
+		closed:         make(chan struct{}),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o750); err != nil {
+		return nil, err
+	}
+
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	d.detach = hubOf(conductor).addInterceptors(persistScope{}, []Interceptor[T]{d.persist})
+	d.unhook = hubOf(conductor).addHook(d.push)
+	go d.run()
+
+	return d, nil
+}
+
+// Cmd returns the channel where the commands are delivered. Each of them must be
+// acknowledged, using its ID, once handled.
+func (d *DurableListener[T]) Cmd() <-chan Envelope[T] {
+	return d.out
+}
+
+// Ack marks the command with the given ID as handled, so that it is not delivered
+// anymore.
+func (d *DurableListener[T]) Ack(id string) error {
+	d.mu.Lock()
+	if d.index(id) < 0 {
+		d.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNotPending, id)
+	}
+	if err := d.write(durableAck, Envelope[T]{ID: id}); err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	f, size := d.f, d.size
+	d.mu.Unlock()
+
+	if err := d.sync(f, size); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	idx := d.index(id)
+	if idx < 0 {
+		// XXX: acknowledged twice at the same time.
+		return nil
+	}
+	d.pending = append(d.pending[:idx], d.pending[idx+1:]...)
+
+	if d.inflight == id {
+		d.inflight = ""
+	}
+	if len(d.pending) == 0 && d.size > durableCompactSize {
+		if err := d.compact(); err != nil {
+			fmt.Fprintf(logFile, "Failed to compact %s: %s\n", d.path, err)
+		}
+	}
+	d.signal()
+
+	return nil
+}
+
+// Pending returns the number of commands waiting to be acknowledged.
+func (d *DurableListener[T]) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.pending)
+}
+
+// Close stops the [DurableListener]. The commands still pending are kept on disk.
+func (d *DurableListener[T]) Close() error {
+	var err error
+	d.once.Do(func() {
+		d.detach()
+		d.unhook()
+		close(d.closed)
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		err = d.f.Close()
+	})
+
+	return err
+}
+
+/* Internal functions */
+
+func (d *DurableListener[T]) matches(env Envelope[T]) bool {
+	if !d.sees(env) {
+		return false
+	}
+	if d.tag == "" || len(env.Tags) == 0 {
+		return true
+	}
+	for _, tag := range env.Tags {
+		if tag == any(d.tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// persist is the [Interceptor] persisting the commands sent, telling the sender if
+// that fails.
+func (d *DurableListener[T]) persist(env Envelope[T], next func(Envelope[T]) error) error {
+	if d.matches(env) {
+		if err := d.enqueue(env); err != nil {
+			return fmt.Errorf("cannot persist to %s: %w", d.path, err)
+		}
+	}
+	return next(env)
+}
+
+// push is the hook persisting the commands that are not sent, e.g. fired by a policy.
+// The ones sent were persisted already, and are skipped.
+func (d *DurableListener[T]) push(env Envelope[T]) {
+	if !d.matches(env) {
+		return
+	}
+
+	if err := d.enqueue(env); err != nil {
+		fmt.Fprintf(logFile, "Failed to persist %s: %s\n", fmtCmd(env.Cmd), err)
+	}
+}
+
+// enqueue persists the command and queues it, unless it was received already. A
+// command that cannot be persisted is not queued.
+func (d *DurableListener[T]) enqueue(env Envelope[T]) error {
+	d.mu.Lock()
+	if !d.seen.Add(env.ID) {
+		d.mu.Unlock()
+		return nil
+	}
+	if err := d.write(durablePush, env); err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	// XXX: the command is queued before being synced, so that a compaction
+	// meanwhile keeps it.
+	d.pending = append(d.pending, env)
+	f, size := d.f, d.size
+	d.mu.Unlock()
+
+	if err := d.sync(f, size); err != nil {
+		d.mu.Lock()
+		if idx := d.index(env.ID); idx >= 0 {
+			d.pending = append(d.pending[:idx], d.pending[idx+1:]...)
+		}
+		d.mu.Unlock()
+		return err
+	}
+
+	d.signal()
+	return nil
+}
+
+// sync makes sure that the given queue file is on disk up to the given size. The
+// callers waiting meanwhile are served by a single fsync, so that concurrent senders
+// do not wait for one another's.
+func (d *DurableListener[T]) sync(f *os.File, size int64) error {
+	d.syncMu.Lock()
+	defer d.syncMu.Unlock()
+
+	d.mu.Lock()
+	// XXX: a compaction meanwhile rewrote and synced the whole queue.
+	if d.f != f || d.synced >= size {
+		d.mu.Unlock()
+		return nil
+	}
+	size = d.size
+	d.mu.Unlock()
+
+	err := f.Sync()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.f != f {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	d.synced = max(d.synced, size)
+	return nil
+}
+
+func (d *DurableListener[T]) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *DurableListener[T]) record(op string, env Envelope[T]) ([]byte, error) {
+	record := durableRecord{
+		Op: op,
+		ID: env.ID,
+	}
+
+	if op == durablePush {
+		data, err := d.codec.Encode(env.Cmd)
+		if err != nil {
+			return nil, err
+		}
+		record.Time = env.Time
+		record.Origin = env.Origin
+		record.Meta = env.Meta
+		record.Cmd = data
+		for _, tag := range env.Tags {
+			record.Tags = append(record.Tags, fmt.Sprint(tag))
+		}
+	}
+
+	line, err := json.Marshal(record)
+	return append(line, '\n'), err
+}
+
+func (d *DurableListener[T]) write(op string, env Envelope[T]) error {
+	line, err := d.record(op, env)
+	if err != nil {
+		return err
+	}
+
+	n, err := d.f.Write(line)
+	d.size += int64(n)
+	return err
+}
+
+// load reads back the queue left on disk, and rewrites it with only what is needed.
+func (d *DurableListener[T]) load() error {
+	if f, err := os.Open(d.path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 16<<20)
+		for scanner.Scan() {
+			var record durableRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				// XXX: most likely a line truncated by a crash.
+				continue
+			}
+			d.seen.Add(record.ID)
+
+			switch record.Op {
+			case durablePush:
+				cmd, err := d.codec.Decode(record.Cmd)
+				if err != nil {
+					f.Close()
+					return err
+				}
+				env := Envelope[T]{
+					ID:     record.ID,
+					Cmd:    cmd,
+					Time:   record.Time,
+					Origin: record.Origin,
+					Meta:   record.Meta,
+				}
+				for _, tag := range record.Tags {
+					env.Tags = append(env.Tags, tag)
+				}
+				d.pending = append(d.pending, env)
+			case durableAck:
+				if idx := d.index(record.ID); idx >= 0 {
+					d.pending = append(d.pending[:idx], d.pending[idx+1:]...)
+				}
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return d.compact()
+}
+
+// compact rewrites the queue file with the pending commands, and with the IDs of the
+// acknowledged ones still remembered, so that they are discarded after a restart.
+func (d *DurableListener[T]) compact() error {
+	pending := make(map[string]struct{}, len(d.pending))
+	for _, env := range d.pending {
+		pending[env.ID] = struct{}{}
+	}
+
+	var buf []byte
+	for _, id := range d.seen.IDs() {
+		if _, ok := pending[id]; ok {
+			continue
+		}
+		line, err := d.record(durableAck, Envelope[T]{ID: id})
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+	}
+	for _, env := range d.pending {
+		line, err := d.record(durablePush, env)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+	}
+
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0o640); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, d.path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(d.path, os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if d.f != nil {
+		d.f.Close()
+	}
+	d.f = f
+	d.size = int64(len(buf))
+	d.synced = d.size
+
+	return nil
+}
+
+func (d *DurableListener[T]) index(id string) int {
+	for i, env := range d.pending {
+		if env.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *DurableListener[T]) run() {
+	var redeliver <-chan time.Time
+
+	for {
+		d.mu.Lock()
+		var out chan Envelope[T]
+		var head Envelope[T]
+		if len(d.pending) > 0 && d.inflight == "" {
+			out = d.out
+			head = d.pending[0]
+		}
+		if d.inflight == "" {
+			redeliver = nil
+		}
+		d.mu.Unlock()
+
+		select {
+		case out <- head:
+			d.mu.Lock()
+			d.inflight = head.ID
+			d.mu.Unlock()
+			redeliver = time.After(d.redeliverAfter)
+		case <-redeliver:
+			// XXX: the command in flight was not acknowledged in time, so it is
+			// delivered again.
+			d.mu.Lock()
+			d.inflight = ""
+			d.mu.Unlock()
+		case <-d.wake:
+		case <-d.closed:
+			return
+		case <-d.conductor.Done():
+			return
+		}
+	}
+}
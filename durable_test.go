@@ -0,0 +1,195 @@
+package conductor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, d *DurableListener[string]) Envelope[string] {
+	t.Helper()
+
+	select {
+	case env := <-d.Cmd():
+		return env
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+	panic("unreachable")
+}
+
+func TestDurable_redeliverAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	c := Tagged[string]()
+
+	d, err := Durable(WithTag(c, "first"), dir, "flusher", StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Send(c, "first")("ciao")
+	Send(c, "second")("ignored")
+	Send(c)("miao")
+
+	env := receive(t, d)
+	if env.Cmd != "ciao" {
+		t.Fatalf("Unexpected cmd: %s", env.Cmd)
+	}
+	if err := d.Ack(env.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(d.Ack(env.ID), ErrNotPending) {
+		t.Fatal("Acknowledging twice should fail")
+	}
+
+	// The second command is received, but the process "crashes" before acknowledging.
+	lost := receive(t, d)
+	if lost.Cmd != "miao" {
+		t.Fatalf("Unexpected cmd: %s", lost.Cmd)
+	}
+	d.Close()
+
+	d, err = Durable(WithTag(c, "first"), dir, "flusher", StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if d.Pending() != 1 {
+		t.Fatalf("Unexpected pending commands: %d", d.Pending())
+	}
+
+	env = receive(t, d)
+	if env.ID != lost.ID || env.Cmd != "miao" {
+		t.Fatalf("Unexpected redelivery: %+v", env)
+	}
+	if err := d.Ack(env.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Already acknowledged commands are discarded, even after a restart.
+	Deliver[string](c, lost)
+	if d.Pending() != 0 {
+		t.Fatalf("Duplicated command was queued: %d", d.Pending())
+	}
+}
+
+func TestDurable_sub(t *testing.T) {
+	c := Tagged[string]()
+	db := Sub(c, "db")
+
+	d, err := Durable[string](db, t.TempDir(), "flusher", StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	Send(c, "web")("outside")
+	Send(db, "primary")("inside")
+	Send(c)("everyone")
+
+	for _, expected := range []string{"inside", "everyone"} {
+		env := receive(t, d)
+		if env.Cmd != expected {
+			t.Fatalf("Unexpected cmd: %s (expected %s)", env.Cmd, expected)
+		}
+		if err := d.Ack(env.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d.Pending() != 0 {
+		t.Fatalf("Unexpected pending commands: %d", d.Pending())
+	}
+}
+
+func TestDurable_redeliverAfterTimeout(t *testing.T) {
+	c := Simple[string]()
+
+	d, err := Durable(c, t.TempDir(), "flusher", StringCodec[string](), 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	lis := c.Cmd()
+	Send(c)("ciao")
+	<-lis
+
+	first := receive(t, d)
+	second := receive(t, d)
+	if first.ID != second.ID {
+		t.Fatalf("Unexpected redelivery: %+v, %+v", first, second)
+	}
+
+	if err := d.Ack(second.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case env := <-d.Cmd():
+		t.Fatalf("Unexpected redelivery after ack: %+v", env)
+	case <-time.After(successTimeout):
+	}
+}
+
+func TestDurable_persistFailure(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	d, err := Durable(c, t.TempDir(), "flusher", StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The disk "fails" under the listener.
+	d.mu.Lock()
+	d.f.Close()
+	d.mu.Unlock()
+
+	if err := TrySend(c)("ciao"); err == nil {
+		t.Fatal("Persistence failure not reported")
+	}
+	if d.Pending() != 0 {
+		t.Fatalf("Unexpected pending commands: %d", d.Pending())
+	}
+	select {
+	case cmd := <-lis:
+		t.Fatalf("Unpersisted command delivered: %s", cmd)
+	case <-time.After(successTimeout):
+	}
+}
+
+func TestDurable_concurrentSends(t *testing.T) {
+	c := Simple[string]()
+	dir := t.TempDir()
+
+	d, err := Durable(c, dir, "flusher", StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const senders = 20
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := TrySend(c)("flush"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	d.Close()
+
+	d, err = Durable(c, dir, "flusher", StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if d.Pending() != senders {
+		t.Fatalf("Unexpected pending commands: %d", d.Pending())
+	}
+}
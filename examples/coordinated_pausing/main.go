@@ -126,9 +126,9 @@ func init() {
 func main() {
 	c := conductor.SimpleFromContext[Action](context.Background())
 
-	go conductor.Notify(c)(ActionStop, os.Interrupt)
-	go conductor.Notify(c)(ActionPause, syscall.SIGUSR1)
-	go conductor.Notify(c)(ActionUnpause, syscall.SIGUSR2)
+	conductor.Notify(c)(ActionStop, os.Interrupt)
+	conductor.Notify(c)(ActionPause, syscall.SIGUSR1)
+	conductor.Notify(c)(ActionUnpause, syscall.SIGUSR2)
 
 	collectors := make([]chan time.Time, workers)
 	deltas := make([]any, workers)
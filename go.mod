@@ -1,3 +1,3 @@
 module git.sr.ht/~blallo/conductor
 
-go 1.20
+go 1.21
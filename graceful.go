@@ -0,0 +1,194 @@
+package conductor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// settleInterval is how often a graceful conductor checks if its listeners
+	// received the commands fired by the policies.
+	settleInterval = time.Millisecond
+	// defaultSettleTimeout bounds the wait of a graceful conductor created without
+	// a timeout.
+	defaultSettleTimeout = 5 * time.Second
+)
+
+type graceful[T any] struct {
+	wrapped Conductor[T]
+	timeout time.Duration
+	*settling
+}
+
+// settling is shared by a graceful conductor and the ones derived from it with
+// [WithTag] and [Sub], that are done together: the wait is started only once.
+type settling struct {
+	done chan struct{}
+	once sync.Once
+}
+
+/* Implement context.Context */
+
+var _ context.Context = &graceful[struct{}]{}
+
+func (g *graceful[T]) Deadline() (time.Time, bool) {
+	return g.wrapped.Deadline()
+}
+
+func (g *graceful[T]) Done() <-chan struct{} {
+	g.once.Do(func() {
+		go g.settle()
+	})
+	return g.done
+}
+
+func (g *graceful[T]) Err() error {
+	select {
+	case <-g.Done():
+		return g.wrapped.Err()
+	default:
+		return nil
+	}
+}
+
+func (g *graceful[T]) Value(key any) any {
+	return g.wrapped.Value(key)
+}
+
+/* Implement Conductor[T] */
+
+func (g *graceful[T]) Cmd() <-chan T {
+	return g.wrapped.Cmd()
+}
+
+func (g *graceful[T]) WithContext(ctx context.Context) Conductor[T] {
+	return Graceful(g.wrapped.WithContext(ctx), g.timeout)
+}
+
+func (g *graceful[T]) WithContextPolicy(policy Policy[T]) Conductor[T] {
+	g.wrapped.WithContextPolicy(policy)
+	return g
+}
+
+/* Internal functions */
+
+// derive returns a graceful conductor wrapping the given one, derived from this one:
+// it shares the same wait.
+func (g *graceful[T]) derive(conductor Conductor[T]) Conductor[T] {
+	return &graceful[T]{
+		wrapped:  conductor,
+		timeout:  g.timeout,
+		settling: g.settling,
+	}
+}
+
+// settle closes the done channel once the wrapped conductor is done, the policies
+// fired and the listeners received their commands, or once the timeout expired.
+func (g *graceful[T]) settle() {
+	h := hubOf(g.wrapped)
+	defer close(g.done)
+	defer h.settled()
+
+	<-g.wrapped.Done()
+
+	timer := time.NewTimer(g.timeout)
+	defer timer.Stop()
+	expired := timer.C
+	for _, fired := range h.firing() {
+		select {
+		case <-fired:
+		case <-expired:
+			return
+		}
+	}
+
+	// XXX: a command sitting in the buffer of a listener may still lose the race
+	// against Done in its select, so we wait for the listeners to receive the
+	// commands fired by the policies.
+	ticker := time.NewTicker(settleInterval)
+	defer ticker.Stop()
+	for !h.received() {
+		select {
+		case <-ticker.C:
+		case <-expired:
+			return
+		}
+	}
+}
+
+// pushed records that a command was pushed to the given listener, marking it if the
+// command was fired by a policy.
+func (h *hub[T]) pushed(ch chan T, policy bool) {
+	if !policy && !h.marking.Load() {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if policy {
+		h.marks[ch] = 0
+		h.marking.Store(true)
+	} else if n, ok := h.marks[ch]; ok {
+		h.marks[ch] = n + 1
+	}
+}
+
+func (h *hub[T]) unmark(lis <-chan T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.marks {
+		if (<-chan T)(ch) == lis {
+			delete(h.marks, ch)
+			return
+		}
+	}
+}
+
+// settled forgets the marked listeners, once a graceful conductor is done waiting for
+// them, so that the commands pushed afterwards are not counted anymore.
+func (h *hub[T]) settled() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clear(h.marks)
+	h.marking.Store(false)
+}
+
+// received tells if the marked listeners received the command fired by a policy: that
+// is, if they have no more commands in their buffer than the ones pushed after it.
+func (h *hub[T]) received() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch, n := range h.marks {
+		if len(ch) > n {
+			return false
+		}
+	}
+	return true
+}
+
+/* Public functions */
+
+// Graceful returns a [Conductor] that reports to be done only after the given one is
+// done, the commands fired by its policies have been delivered (and acknowledged, for
+// the steps of a [MultiPolicy] having an AckTimeout) and every listener received them.
+// This way a listener selecting both on [Conductor.Cmd] and [Conductor.Done] is
+// guaranteed to get the last command before shutting down. The timeout bounds the
+// wait, in case some listener is not receiving anymore: if not positive, it is 5s.
+func Graceful[T any](conductor Conductor[T], timeout time.Duration) Conductor[T] {
+	if timeout <= 0 {
+		timeout = defaultSettleTimeout
+	}
+
+	return &graceful[T]{
+		wrapped: conductor,
+		timeout: timeout,
+		settling: &settling{
+			done: make(chan struct{}),
+		},
+	}
+}
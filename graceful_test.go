@@ -0,0 +1,168 @@
+package conductor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGracefulSimple(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := Graceful(Simple[string]().
+			WithContext(ctx).
+			WithContextPolicy(ConstantPolicy("stop")), 0)
+
+		lis := c.Cmd()
+		last := make(chan string)
+		go func() {
+			var got string
+			for {
+				select {
+				case got = <-lis:
+				case <-c.Done():
+					last <- got
+					return
+				}
+			}
+		}()
+
+		cancel()
+
+		select {
+		case got := <-last:
+			if got != "stop" {
+				t.Fatalf("[%d] Done before the policy command, last: %q", i, got)
+			}
+		case <-time.After(failureTimeout):
+			t.Fatalf("[%d] Not done", i)
+		}
+
+		if c.Err() != context.Canceled {
+			t.Fatalf("Unexpected error: %v", c.Err())
+		}
+	}
+}
+
+func TestGracefulTagged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := Graceful(TaggedFromContext[string](ctx), 0).
+		WithContextPolicy(SequencePolicy(
+			Step[string]{Cmd: "pause"},
+			Step[string]{Cmd: "stop", Delay: 10 * time.Millisecond},
+		))
+
+	red := WithTag(c, "red")
+	lis := red.Cmd()
+
+	cancel()
+
+	var got []string
+	func() {
+		for {
+			select {
+			case cmd := <-lis:
+				got = append(got, cmd)
+			case <-red.Done():
+				return
+			}
+		}
+	}()
+
+	if len(got) != 2 || got[0] != "pause" || got[1] != "stop" {
+		t.Fatalf("Unexpected: %v", got)
+	}
+}
+
+func TestGracefulTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := Graceful(SimpleFromContext[string](ctx).
+		WithContextPolicy(ConstantPolicy("stop")), 20*time.Millisecond)
+
+	// A listener that never receives does not hold back Done forever.
+	_ = c.Cmd()
+
+	if c.Err() != nil {
+		t.Fatalf("Unexpected error: %v", c.Err())
+	}
+
+	cancel()
+
+	select {
+	case <-c.Done():
+		t.Fatal("Done before the timeout")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	select {
+	case <-c.Done():
+	case <-time.After(failureTimeout):
+		t.Fatal("Not done after the timeout")
+	}
+}
+
+func TestGracefulOnlyPolicyCommands(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := TaggedFromContext[string](ctx)
+
+	// A listener not concerned by the policy, that never receives.
+	WithTag(c, "web").Cmd()
+	Send(c, "web")("busy")
+
+	db := Graceful(Sub(c, "db"), 0).WithContextPolicy(ConstantPolicy("stop"))
+	lis := db.Cmd()
+	last := make(chan string)
+	go func() {
+		var got string
+		for {
+			select {
+			case got = <-lis:
+			case <-db.Done():
+				last <- got
+				return
+			}
+		}
+	}()
+
+	cancel()
+
+	select {
+	case got := <-last:
+		if got != "stop" {
+			t.Fatalf("Done before the policy command, last: %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Held by a listener not concerned by the policy")
+	}
+}
+
+func TestGracefulDerived(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := Graceful(TaggedFromContext[string](ctx), 0).
+		WithContextPolicy(ConstantPolicy("stop"))
+
+	red := WithTag(c, "red")
+	lis := red.Cmd()
+
+	// The conductors derived from a graceful one share its wait.
+	if red.Done() != c.Done() || Sub(c, "db").Done() != c.Done() {
+		t.Fatal("Derived conductors wait on their own")
+	}
+
+	cancel()
+
+	select {
+	case cmd := <-lis:
+		if cmd != "stop" {
+			t.Fatalf("Unexpected: %s", cmd)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+	<-c.Done()
+
+	// Once settled, the listeners are not marked anymore.
+	if h := hubOf(c); h.marking.Load() || len(h.marks) != 0 {
+		t.Fatal("Listeners still marked after settling")
+	}
+}
@@ -0,0 +1,245 @@
+package conductor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// HandlerFunc handles a command received by a [Handler].
+type HandlerFunc[T any] func(ctx context.Context, cmd T) error
+
+// PanicError is the error reported when a [HandlerFunc] panics.
+type PanicError struct {
+	// Value is the value the handler panicked with.
+	Value any
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panicked: %v", e.Value)
+}
+
+// Pool is a pool of workers shared by many [Handler]s, bounding the commands handled
+// at the same time by all of them. See [OnPool].
+type Pool struct {
+	sem chan struct{}
+}
+
+// NewPool creates a [Pool] of the given number of workers.
+func NewPool(workers int) *Pool {
+	return &Pool{
+		sem: make(chan struct{}, max(workers, 1)),
+	}
+}
+
+type handleOptions struct {
+	pool        *Pool
+	concurrency int
+	timeout     time.Duration
+	onError     func(error)
+}
+
+// HandleOption configures a [Handler], see [Handle].
+type HandleOption func(*handleOptions)
+
+// OnPool makes the [Handler] run on the given [Pool], instead of using a goroutine for
+// each command.
+func OnPool(pool *Pool) HandleOption {
+	return func(o *handleOptions) {
+		o.pool = pool
+	}
+}
+
+// MaxConcurrency sets how many commands a [Handler] may handle at the same time, 1 by
+// default, so that the commands are handled one after the other, in order.
+func MaxConcurrency(n int) HandleOption {
+	return func(o *handleOptions) {
+		o.concurrency = max(n, 1)
+	}
+}
+
+// HandleTimeout bounds the time given to the [HandlerFunc] to handle each command,
+// canceling its context when it expires.
+func HandleTimeout(d time.Duration) HandleOption {
+	return func(o *handleOptions) {
+		o.timeout = d
+	}
+}
+
+// OnError makes the [Handler] report the errors of the [HandlerFunc] to the given
+// function, as they happen, instead of collecting them for [Handler.Wait].
+func OnError(fn func(error)) HandleOption {
+	return func(o *handleOptions) {
+		o.onError = fn
+	}
+}
+
+// Handler is a listener running a [HandlerFunc] for each command it receives. See
+// [Handle].
+type Handler struct {
+	stop chan struct{}
+	once sync.Once
+	done chan struct{}
+	mu   sync.Mutex
+	errs []error
+}
+
+// Spawn runs fn from a goroutine of its own, with a listener of the given [Conductor]
+// registered there, and returns once it is registered, so that no command sent
+// afterwards is missed. As the listeners are told apart by their call site and
+// goroutine, each call gets a listener of its own, that fn releases with [Release].
+func Spawn[T any](conductor Conductor[T], fn func(lis <-chan T)) {
+	ready := make(chan struct{})
+	go func() {
+		lis := conductor.Cmd()
+		close(ready)
+		fn(lis)
+	}()
+	<-ready
+}
+
+// Handle registers a listener of the given tag (that must be empty for a Simple
+// [Conductor], and gives a listener without a tag for a Tagged one) and calls fn for each
+// command it receives, in place of a hand-written select loop. The context given to fn
+// is canceled when the [Conductor] is done, or when the timeout set with
+// [HandleTimeout] expires. A panic of fn is recovered and reported as a [*PanicError].
+// The requests made with [Request] are answered with the error returned by fn, that
+// is nil on success, in the order the commands were received, even when they are handled
+// concurrently. The [Handler] stops receiving commands once the [Conductor] is done
+// or it is stopped, and waits for the commands being handled.
+func Handle[T any](conductor Conductor[T], tag string, fn HandlerFunc[T], opts ...HandleOption) *Handler {
+	o := handleOptions{
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	h := &Handler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	source := conductor
+	if tag != "" {
+		source = WithTag(conductor, tag)
+	}
+
+	Spawn(source, func(lis <-chan T) {
+		defer close(h.done)
+		defer Release(conductor, lis)
+
+		limit := make(chan struct{}, o.concurrency)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		// XXX: Reply answers the oldest pending request, so each command waits for
+		// the previous one to be replied before its own reply is given.
+		replied := make(chan struct{})
+		close(replied)
+
+		for {
+			select {
+			case <-conductor.Done():
+				return
+			case <-h.stop:
+				return
+			case cmd := <-lis:
+				if !h.acquire(conductor, limit) {
+					return
+				}
+				if o.pool != nil && !h.acquire(conductor, o.pool.sem) {
+					<-limit
+					return
+				}
+
+				turn, next := replied, make(chan struct{})
+				replied = next
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() { <-limit }()
+
+					err := handleCmd(conductor, fn, cmd, o.timeout)
+					if o.pool != nil {
+						<-o.pool.sem
+					}
+
+					<-turn
+					Reply(lis, err)
+					close(next)
+
+					if err != nil {
+						h.report(err, o.onError)
+					}
+				}()
+			}
+		}
+	})
+
+	return h
+}
+
+func (h *Handler) acquire(ctx context.Context, sem chan struct{}) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-h.stop:
+		return false
+	}
+}
+
+func handleCmd[T any](ctx context.Context, fn HandlerFunc[T], cmd T, timeout time.Duration) (err error) {
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{
+				Value: v,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+
+	return fn(ctx, cmd)
+}
+
+func (h *Handler) report(err error, onError func(error)) {
+	if onError != nil {
+		onError(err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errs = append(h.errs, err)
+}
+
+// Stop makes the [Handler] stop receiving commands, as if the [Conductor] was done.
+func (h *Handler) Stop() {
+	h.once.Do(func() { close(h.stop) })
+}
+
+// Wait waits for the [Handler] to stop and for the commands being handled, and returns
+// the errors collected meanwhile, joined with [errors.Join].
+func (h *Handler) Wait() error {
+	<-h.done
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return errors.Join(h.errs...)
+}
@@ -0,0 +1,266 @@
+package conductor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := Tagged[string]().WithContext(ctx)
+
+	got := make(chan string, 10)
+	h := Handle(c, "worker", func(_ context.Context, cmd string) error {
+		got <- cmd
+		return nil
+	})
+
+	Send(c, "worker")("pause")
+	Send(c, "other")("ignored")
+	Send(c, "worker")("resume")
+
+	for _, expected := range []string{"pause", "resume"} {
+		select {
+		case cmd := <-got:
+			if cmd != expected {
+				t.Fatalf("Unexpected command: %s (expected %s)", cmd, expected)
+			}
+		case <-time.After(failureTimeout):
+			t.Fatalf("Command %s not handled", expected)
+		}
+	}
+
+	cancel()
+	if err := h.Wait(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+func TestHandleErrors(t *testing.T) {
+	c := Simple[int]()
+
+	failure := errors.New("failure")
+	h := Handle(c, "", func(_ context.Context, cmd int) error {
+		switch cmd {
+		case 1:
+			return failure
+		case 2:
+			panic("boom")
+		}
+		return nil
+	})
+
+	replies, err := Request(c)(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0] != failure {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+
+	replies, err = Request(c)(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var perr *PanicError
+	if len(replies) != 1 || !errors.As(replies[0].(error), &perr) || perr.Value != "boom" {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+
+	replies, err = Request(c)(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0] != nil {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+
+	h.Stop()
+	err = h.Wait()
+	if !errors.Is(err, failure) || !errors.As(err, &perr) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestHandleTimeout(t *testing.T) {
+	c := Simple[string]()
+
+	var reported []error
+	var mu sync.Mutex
+	h := Handle(c, "", func(ctx context.Context, _ string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, HandleTimeout(successTimeout), OnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, err)
+	}))
+
+	replies, err := Request(c)(context.Background(), "slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0] != context.DeadlineExceeded {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+
+	h.Stop()
+	if err := h.Wait(); err != nil {
+		t.Fatalf("Errors reported to OnError must not be collected: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) != 1 || reported[0] != context.DeadlineExceeded {
+		t.Fatalf("Unexpected reported errors: %v", reported)
+	}
+}
+
+func TestHandleConcurrency(t *testing.T) {
+	c := Tagged[int]()
+	pool := NewPool(3)
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context, int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		return nil
+	}
+
+	first := Handle(c, "first", fn, MaxConcurrency(2), OnPool(pool))
+	second := Handle(c, "second", fn, MaxConcurrency(2), OnPool(pool))
+
+	for i := 0; i < 3; i++ {
+		Send(c, "first")(i)
+		Send(c, "second")(i)
+	}
+
+	time.Sleep(successTimeout)
+	if n := running.Load(); n != 3 {
+		t.Fatalf("The pool must bound the commands handled at once: %d running", n)
+	}
+
+	close(release)
+	first.Stop()
+	second.Stop()
+	first.Wait()
+	second.Wait()
+
+	if p := peak.Load(); p != 3 {
+		t.Fatalf("Unexpected peak of commands handled at once: %d", p)
+	}
+}
+
+func TestHandleInOrder(t *testing.T) {
+	c := Simple[int]()
+
+	var mu sync.Mutex
+	var got []int
+	h := Handle(c, "", func(_ context.Context, cmd int) error {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, cmd)
+		return nil
+	})
+
+	for i := 0; i < 5; i++ {
+		Send(c)(i)
+	}
+	time.Sleep(successTimeout)
+	h.Stop()
+	h.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 5 {
+		t.Fatalf("Unexpected commands handled: %v", got)
+	}
+	for i, cmd := range got {
+		if cmd != i {
+			t.Fatalf("Commands handled out of order: %v", got)
+		}
+	}
+}
+
+func TestHandleConcurrentReplies(t *testing.T) {
+	c := Simple[string]()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := Handle(c, "", func(_ context.Context, cmd string) error {
+		if cmd == "slow" {
+			close(started)
+			<-release
+		}
+		return errors.New(cmd)
+	}, MaxConcurrency(2))
+	defer h.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	slow := make(chan []any, 1)
+	go func() {
+		replies, _ := Request(c)(ctx, "slow")
+		slow <- replies
+	}()
+	<-started
+
+	fast := make(chan []any, 1)
+	go func() {
+		replies, _ := Request(c)(ctx, "fast")
+		fast <- replies
+	}()
+
+	select {
+	case replies := <-fast:
+		t.Fatalf("The fast request was answered before the slow one: %v", replies)
+	case <-time.After(successTimeout):
+	}
+	close(release)
+
+	for cmd, ch := range map[string]chan []any{"slow": slow, "fast": fast} {
+		replies := <-ch
+		if len(replies) != 1 || replies[0].(error).Error() != cmd {
+			t.Fatalf("Unexpected replies to %s: %v", cmd, replies)
+		}
+	}
+}
+
+func TestSpawn(t *testing.T) {
+	c := Simple[string]()
+
+	received := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		Spawn(c, func(lis <-chan string) {
+			defer Release(c, lis)
+			received <- <-lis
+		})
+	}
+
+	// Both listeners are registered once Spawn returns, each its own.
+	Send(c)("ciao")
+	for i := 0; i < 2; i++ {
+		select {
+		case cmd := <-received:
+			if cmd != "ciao" {
+				t.Fatalf("Unexpected: %s", cmd)
+			}
+		case <-time.After(failureTimeout):
+			t.Fatal("Command not received")
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package conductor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	watchBufSize     = 100
+	lifecycleBufSize = 100
+)
+
+type lifecycleKind int
+
+const (
+	listenerReleased lifecycleKind = iota
+	listenerEvicted
+)
+
+// lifecycleEvent tells that a listener left a [Conductor]. The tag is nil for a Simple
+// one.
+type lifecycleEvent struct {
+	kind      lifecycleKind
+	tag       any
+	remaining int
+}
+
+type hook[T any] struct {
+	fn func(Envelope[T])
+}
+
+// pendingPolicy is a [Policy] attached to a [Conductor] and not fired yet.
+type pendingPolicy struct {
+	ctx   context.Context
+	fired chan struct{}
+}
+
+// hub holds what is shared by a [Conductor], its copies and, for a Tagged one, by the
+// listeners of all its tags: those watching the commands sent through it, and its
+// settings.
+type hub[T any] struct {
+	mu         sync.RWMutex
+	subs       map[chan Envelope[T]]func(Envelope[T]) bool
+	hooks      map[*hook[T]]struct{}
+	lifecycle  map[chan lifecycleEvent]struct{}
+	policies   map[*pendingPolicy]struct{}
+	children   map[*hub[T]]node[T]
+	evictAfter atomic.Int64
+
+	// XXX: the listeners that got a command fired by a policy are marked with the
+	// number of commands pushed to them since, so that a graceful conductor can
+	// tell when they received it (see graceful.go).
+	marks   map[chan T]int
+	marking atomic.Bool
+
+	// XXX: interceptors are looked up by scope (see intercept.go), and counted so
+	// that the delivery does not look them up when there are none.
+	interceptors map[any][]*interceptor[T]
+	intercepting atomic.Int32
+
+	// XXX: the listeners someone waits for to leave, e.g. to stop delivering them
+	// the commands held back (see pace.go).
+	leaving map[<-chan T]chan struct{}
+}
+
+func newHub[T any]() *hub[T] {
+	return &hub[T]{
+		subs:      make(map[chan Envelope[T]]func(Envelope[T]) bool),
+		hooks:     make(map[*hook[T]]struct{}),
+		lifecycle: make(map[chan lifecycleEvent]struct{}),
+		policies:  make(map[*pendingPolicy]struct{}),
+		children:  make(map[*hub[T]]node[T]),
+		marks:     make(map[chan T]int),
+
+		interceptors: make(map[any][]*interceptor[T]),
+		leaving:      make(map[<-chan T]chan struct{}),
+	}
+}
+
+func hubOf[T any](conductor Conductor[T]) *hub[T] {
+	switch c := any(unwrap(conductor)).(type) {
+	case *simple[T]:
+		return c.hub
+	case *tagged[T]:
+		return c.hub
+	default:
+		panic("conductor not supported")
+	}
+}
+
+func (h *hub[T]) observe(env Envelope[T]) {
+	// XXX: hooks run synchronously, before the command is delivered, but outside
+	// of the lock, as they may block.
+	for _, hk := range h.snapshotHooks() {
+		hk.fn(env)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch, sees := range h.subs {
+		if !sees(env) {
+			continue
+		}
+		select {
+		case ch <- env:
+		default:
+			// XXX: a watcher must never slow down the senders, so a slow one
+			// just loses envelopes.
+			fmt.Fprintf(logFile, "Dropping %s for a slow watcher\n", fmtCmd(env.Cmd))
+		}
+	}
+}
+
+// gone returns a channel closed once the given listener leaves the conductor.
+func (h *hub[T]) gone(lis <-chan T) <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch, ok := h.leaving[lis]
+	if !ok {
+		ch = make(chan struct{})
+		h.leaving[lis] = ch
+	}
+	return ch
+}
+
+// left tells those waiting for the given listener to leave that it did.
+func (h *hub[T]) left(lis <-chan T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ch, ok := h.leaving[lis]; ok {
+		delete(h.leaving, lis)
+		close(ch)
+	}
+}
+
+// addWatcher returns a channel mirroring the envelopes the given function tells it
+// sees, see [Watch].
+func (h *hub[T]) addWatcher(sees func(Envelope[T]) bool) chan Envelope[T] {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Envelope[T], watchBufSize)
+	h.subs[ch] = sees
+	return ch
+}
+
+func (h *hub[T]) removeWatcher(ch chan Envelope[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+func (h *hub[T]) snapshotHooks() []*hook[T] {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.hooks) == 0 {
+		return nil
+	}
+
+	hooks := make([]*hook[T], 0, len(h.hooks))
+	for hk := range h.hooks {
+		hooks = append(hooks, hk)
+	}
+	return hooks
+}
+
+func (h *hub[T]) addHook(fn func(Envelope[T])) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hk := &hook[T]{fn: fn}
+	h.hooks[hk] = struct{}{}
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.hooks, hk)
+	}
+}
+
+func (h *hub[T]) emit(ev lifecycleEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.lifecycle {
+		select {
+		case ch <- ev:
+		default:
+			fmt.Fprintln(logFile, "Dropping lifecycle event for a slow subscriber")
+		}
+	}
+}
+
+func (h *hub[T]) subscribe() (<-chan lifecycleEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan lifecycleEvent, lifecycleBufSize)
+	h.lifecycle[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			delete(h.lifecycle, ch)
+		})
+	}
+}
+
+// node is a child [Conductor], as seen by its parent.
+type node[T any] interface {
+	dispatch(env Envelope[T])
+}
+
+// addChild registers a child [Conductor], identified by its hub, so that it receives
+// the commands sent through this one.
+func (h *hub[T]) addChild(child *hub[T], n node[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.children[child] = n
+}
+
+func (h *hub[T]) removeChild(child *hub[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.children, child)
+}
+
+func (h *hub[T]) nodes() []node[T] {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	nodes := make([]node[T], 0, len(h.children))
+	for _, n := range h.children {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// propagate hands the envelope over to the children.
+func (h *hub[T]) propagate(env Envelope[T]) {
+	for _, n := range h.nodes() {
+		n.dispatch(env)
+	}
+}
+
+// attachPolicy records a [Policy] that fires once the given context is done, returning
+// the function to call after it fired.
+func (h *hub[T]) attachPolicy(ctx context.Context) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := &pendingPolicy{
+		ctx:   ctx,
+		fired: make(chan struct{}),
+	}
+	h.policies[p] = struct{}{}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			delete(h.policies, p)
+			close(p.fired)
+		})
+	}
+}
+
+// firing returns the channels closed by the policies whose context is done, once they
+// fired.
+func (h *hub[T]) firing() []<-chan struct{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var fired []<-chan struct{}
+	for p := range h.policies {
+		if p.ctx.Err() != nil {
+			fired = append(fired, p.fired)
+		}
+	}
+	return fired
+}
+
+// EvictSlow makes the given [Conductor] evict the listeners that do not receive a
+// command within the given interval, instead of waiting for them indefinitely. An
+// evicted listener gets no more commands, and the requests waiting for its answer
+// receive [ErrEvicted]. Asking for its channel again, from the same place and
+// goroutine, returns a closed channel instead of registering it anew. A non positive
+// interval disables the eviction, which is the default.
+func EvictSlow[T any](conductor Conductor[T], after time.Duration) {
+	hubOf(conductor).evictAfter.Store(int64(after))
+}
@@ -0,0 +1,64 @@
+package conductor
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Tags returns the tags that have at least one listener in the given [Conductor].
+// The listeners not bound to any tag are not reported. For a Simple [Conductor]
+// it always returns an empty list.
+func Tags[T any](conductor Conductor[T]) []string {
+	if s, ok := subOf(conductor); ok {
+		return s.tags()
+	}
+
+	c, ok := any(unwrap(conductor)).(*tagged[T])
+	if !ok {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tags := make([]string, 0, len(c.tagged))
+	for tag := range c.tagged {
+		if tag == defaultTag {
+			continue
+		}
+		tags = append(tags, fmt.Sprint(tag))
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+// Listeners returns the identifiers of the listeners registered in the given [Conductor],
+// grouped by tag. The listeners not bound to any tag are found at the empty tag.
+func Listeners[T any](conductor Conductor[T]) map[string][]string {
+	if s, ok := subOf(conductor); ok {
+		return s.listeners()
+	}
+
+	switch c := any(unwrap(conductor)).(type) {
+	case *simple[T]:
+		return map[string][]string{
+			"": c.keys(),
+		}
+	case *tagged[T]:
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		listeners := make(map[string][]string, len(c.tagged))
+		for tag, s := range c.tagged {
+			name := fmt.Sprint(tag)
+			if tag == defaultTag {
+				name = ""
+			}
+			listeners[name] = s.keys()
+		}
+		return listeners
+	default:
+		panic("conductor not supported")
+	}
+}
@@ -0,0 +1,237 @@
+package conductor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDropped may be returned by an [Interceptor] to drop a command without rejecting
+// it: [TrySend] does not return it, while it is the reply of each listener a
+// [Request] waits for.
+var ErrDropped = errors.New("command dropped")
+
+// Interceptor intercepts a command on its way through a [Conductor]. It lets it through
+// calling next, possibly with a modified [Envelope], e.g. to transform the command or
+// to add metadata, and returns the error of next; or it rejects the command returning
+// an error without calling next. It may also do something before and after calling
+// next, e.g. to log or to trace the command, or hold it back, e.g. to rate limit it.
+type Interceptor[T any] func(env Envelope[T], next func(Envelope[T]) error) error
+
+// interceptor is an [Interceptor] attached to a [Conductor], identified by its address.
+type interceptor[T any] struct {
+	fn Interceptor[T]
+}
+
+// The scopes the interceptors are attached to, besides the listeners.
+type (
+	// sendScope is the sending of the commands through the conductor.
+	sendScope struct{}
+	// sendTagScope is the sending of the commands to a tag.
+	sendTagScope struct{ tag any }
+	// sendSubScope is the sending of the commands within a sub-conductor.
+	sendSubScope struct{ prefix string }
+	// everyScope is the delivery to all the listeners of the conductor.
+	everyScope struct{}
+	// tagScope is the delivery to the listeners of a tag.
+	tagScope struct{ tag any }
+	// subScope is the delivery to the listeners of a sub-conductor.
+	subScope struct{ prefix string }
+	// persistScope is the persistence of the commands sent, right before they are
+	// dispatched, once every other interceptor let them through (see durable.go).
+	persistScope struct{}
+)
+
+// addInterceptors attaches the given interceptors to the given scope, after the ones
+// already attached, returning the function that detaches them.
+func (h *hub[T]) addInterceptors(scope any, fns []Interceptor[T]) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	added := make([]*interceptor[T], 0, len(fns))
+	for _, fn := range fns {
+		added = append(added, &interceptor[T]{fn: fn})
+	}
+	h.interceptors[scope] = append(h.interceptors[scope], added...)
+	h.intercepting.Add(int32(len(added)))
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		chain := h.interceptors[scope]
+		kept := make([]*interceptor[T], 0, len(chain))
+		for _, ic := range chain {
+			removed := false
+			for _, a := range added {
+				removed = removed || ic == a
+			}
+			if !removed {
+				kept = append(kept, ic)
+			}
+		}
+		h.intercepting.Add(int32(len(kept) - len(chain)))
+		added = nil
+
+		if len(kept) == 0 {
+			delete(h.interceptors, scope)
+		} else {
+			h.interceptors[scope] = kept
+		}
+	}
+}
+
+// chain returns the interceptors attached to the given scopes, in order.
+func (h *hub[T]) chain(scopes ...any) []*interceptor[T] {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var chain []*interceptor[T]
+	for _, scope := range scopes {
+		chain = append(chain, h.interceptors[scope]...)
+	}
+	return chain
+}
+
+// intercept runs the interceptors attached to the sending of the commands around the
+// given function, that dispatches the command.
+func (h *hub[T]) intercept(env Envelope[T], dispatch func(Envelope[T])) error {
+	if h.intercepting.Load() == 0 {
+		dispatch(env)
+		return nil
+	}
+
+	return runChain(h.sendChain(env.Tags), env, func(env Envelope[T]) error {
+		dispatch(env)
+		return nil
+	})
+}
+
+// sendChain returns the interceptors of the sending of a command to the given tags: the
+// ones of the whole conductor first, then, for each tag, the ones of the
+// sub-conductors it belongs to, from the outermost, and the ones of the tag, and last
+// the ones persisting the command. Each scope is run once, even if many tags share it.
+func (h *hub[T]) sendChain(tags []any) []*interceptor[T] {
+	scopes := []any{sendScope{}}
+	seen := make(map[any]struct{})
+	add := func(scope any) {
+		if _, ok := seen[scope]; !ok {
+			seen[scope] = struct{}{}
+			scopes = append(scopes, scope)
+		}
+	}
+
+	for _, tag := range tags {
+		if name, ok := tag.(string); ok {
+			parts := strings.Split(name, SubSeparator)
+			for i := range parts {
+				add(sendSubScope{prefix: strings.Join(parts[:i+1], SubSeparator)})
+			}
+		}
+		add(sendTagScope{tag: tag})
+	}
+	scopes = append(scopes, persistScope{})
+
+	return h.chain(scopes...)
+}
+
+// receiveChain returns the interceptors of the delivery to the given listener of the
+// given tag: the ones of the whole conductor first, then the ones of the
+// sub-conductors, from the outermost, the ones of the tag and last the ones of the
+// listener.
+func (h *hub[T]) receiveChain(tag any, lis <-chan T) []*interceptor[T] {
+	if h.intercepting.Load() == 0 {
+		return nil
+	}
+
+	scopes := []any{everyScope{}}
+	if name, ok := tag.(string); ok {
+		parts := strings.Split(name, SubSeparator)
+		for i := range parts {
+			scopes = append(scopes, subScope{prefix: strings.Join(parts[:i+1], SubSeparator)})
+		}
+	}
+	scopes = append(scopes, tagScope{tag: tag}, lis)
+
+	return h.chain(scopes...)
+}
+
+func runChain[T any](chain []*interceptor[T], env Envelope[T], last func(Envelope[T]) error) error {
+	if len(chain) == 0 {
+		return last(env)
+	}
+	return chain[0].fn(env, func(env Envelope[T]) error {
+		return runChain(chain[1:], env, last)
+	})
+}
+
+/* Public functions */
+
+// InterceptSend attaches the given interceptors to the sending of the commands through
+// the given [Conductor], as done by [Send], [TrySend] and [Request], before they are
+// observed and delivered: of all of them, of the ones sent to a tag if it is returned
+// by [WithTag], or of the ones sent within a namespace if it is returned by [Sub]. The
+// interceptors run in the order they are attached, once per command, and a command
+// they reject is not delivered at all. The commands fired by a [Policy] once the
+// [Conductor] is done, or delivered with [Deliver], are not sent, and so not
+// intercepted, while the ones fired by [On] are. The returned function detaches the
+// interceptors.
+func InterceptSend[T any](conductor Conductor[T], interceptors ...Interceptor[T]) func() {
+	if g, ok := any(conductor).(*graceful[T]); ok {
+		return InterceptSend(g.wrapped, interceptors...)
+	}
+
+	var scope any = sendScope{}
+	switch c := any(conductor).(type) {
+	case *loaded[T]:
+		scope = sendTagScope{tag: c.tag}
+	case *sub[T]:
+		scope = sendSubScope{prefix: c.prefix}
+	}
+	return hubOf(conductor).addInterceptors(scope, interceptors)
+}
+
+// InterceptReceive attaches the given interceptors to the delivery of the commands to
+// the listeners of the given [Conductor]: to all of them, to the ones of a tag if it is
+// returned by [WithTag], or to the ones of a namespace if it is returned by [Sub]. The
+// interceptors run once per listener, whatever the way the commands are sent, and a
+// command they reject is not delivered to that listener, whose pending [Request], if
+// any, receives the error as reply. The returned function detaches the interceptors.
+func InterceptReceive[T any](conductor Conductor[T], interceptors ...Interceptor[T]) func() {
+	if g, ok := any(conductor).(*graceful[T]); ok {
+		return InterceptReceive(g.wrapped, interceptors...)
+	}
+
+	var scope any = everyScope{}
+	switch c := any(conductor).(type) {
+	case *loaded[T]:
+		scope = tagScope{tag: c.tag}
+	case *sub[T]:
+		scope = subScope{prefix: c.prefix}
+	}
+	return hubOf(conductor).addInterceptors(scope, interceptors)
+}
+
+// InterceptListener attaches the given interceptors to the delivery of the commands to
+// the given listener, i.e. a channel returned by [Conductor.Cmd], in the same way as
+// [InterceptReceive] does. They run after the ones attached to the [Conductor] and to
+// the tag of the listener. The returned function detaches the interceptors.
+func InterceptListener[T any](conductor Conductor[T], lis <-chan T, interceptors ...Interceptor[T]) func() {
+	return hubOf(conductor).addInterceptors(lis, interceptors)
+}
+
+// rejected logs a command rejected by an interceptor, unless it was dropped.
+func rejected[T any](cmd T, err error) {
+	if errors.Is(err, ErrDropped) {
+		return
+	}
+	fmt.Fprintf(logFile, "Command %s rejected: %s\n", fmtCmd(cmd), err)
+}
+
+// dropped hides from the sender that a command was dropped.
+func dropped(err error) error {
+	if errors.Is(err, ErrDropped) {
+		return nil
+	}
+	return err
+}
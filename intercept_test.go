@@ -0,0 +1,239 @@
+package conductor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errForbidden = errors.New("forbidden")
+
+func forbid(forbidden string) Interceptor[string] {
+	return func(env Envelope[string], next func(Envelope[string]) error) error {
+		if env.Cmd == forbidden {
+			return errForbidden
+		}
+		return next(env)
+	}
+}
+
+func TestInterceptSend(t *testing.T) {
+	c := Tagged[string]()
+	lis := WithTag(c, "worker").Cmd()
+
+	envs, stop := Watch(c)
+	defer stop()
+
+	var order []string
+	detach := InterceptSend(c,
+		forbid("reset"),
+		func(env Envelope[string], next func(Envelope[string]) error) error {
+			order = append(order, "enrich")
+			return next(env.WithMeta("sender", "test"))
+		},
+		func(env Envelope[string], next func(Envelope[string]) error) error {
+			order = append(order, "transform")
+			env.Cmd = strings.ToUpper(env.Cmd)
+			return next(env)
+		},
+	)
+
+	if err := TrySend(c, "worker")("pause"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expectCmd(t, lis, "PAUSE")
+
+	select {
+	case env := <-envs:
+		if env.Cmd != "PAUSE" || env.Meta["sender"] != "test" {
+			t.Fatalf("Unexpected envelope: %+v", env)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Envelope not observed")
+	}
+	if strings.Join(order, ",") != "enrich,transform" {
+		t.Fatalf("Interceptors run out of order: %v", order)
+	}
+
+	if err := TrySend(c, "worker")("reset"); !errors.Is(err, errForbidden) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	Send(c)("reset")
+	expectNoCmd(t, lis)
+
+	replies, err := Request(c, "worker")(context.Background(), "reset")
+	if !errors.Is(err, errForbidden) || len(replies) != 0 {
+		t.Fatalf("Unexpected replies: %v %v", replies, err)
+	}
+
+	detach()
+	Send(c, "worker")("reset")
+	expectCmd(t, lis, "reset")
+}
+
+func TestInterceptSendScoped(t *testing.T) {
+	c := Tagged[string]()
+	red := WithTag(c, "red").Cmd()
+	blue := WithTag(c, "blue").Cmd()
+	primary := WithTag(Sub(c, "db"), "primary").Cmd()
+
+	defer InterceptSend(WithTag(c, "red"), forbid("stop"))()
+	defer InterceptSend(Sub(c, "db"), forbid("flush"))()
+
+	if err := TrySend(c, "blue")("stop"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expectCmd(t, blue, "stop")
+
+	if err := TrySend(c, "red", "blue")("stop"); !errors.Is(err, errForbidden) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectNoCmd(t, red)
+	expectNoCmd(t, blue)
+
+	if err := TrySend(c, "red")("flush"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expectCmd(t, red, "flush")
+
+	if err := TrySend(Sub(c, "db"), "primary")("flush"); !errors.Is(err, errForbidden) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := TrySend(Sub(c, "db"))("flush"); !errors.Is(err, errForbidden) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectNoCmd(t, primary)
+}
+
+func TestInterceptReceiveUnlocked(t *testing.T) {
+	c := Tagged[string]()
+	red := WithTag(c, "red").Cmd()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer InterceptReceive(c, func(env Envelope[string], next func(Envelope[string]) error) error {
+		close(entered)
+		<-release
+		return next(env)
+	})()
+
+	go Send(c, "red")("go")
+	<-entered
+
+	// A listener registers while the interceptor runs.
+	registered := make(chan struct{})
+	go func() {
+		WithTag(c, "blue").Cmd()
+		close(registered)
+	}()
+	select {
+	case <-registered:
+	case <-time.After(failureTimeout):
+		t.Fatal("Registration blocked by an interceptor")
+	}
+
+	close(release)
+	expectCmd(t, red, "go")
+}
+
+func TestInterceptReceive(t *testing.T) {
+	c := Tagged[string]()
+	red := WithTag(c, "red").Cmd()
+	blue := WithTag(c, "blue").Cmd()
+
+	defer InterceptReceive(WithTag(c, "red"), forbid("stop"))()
+
+	Send(c)("stop")
+	expectCmd(t, blue, "stop")
+	expectNoCmd(t, red)
+
+	Send(c)("go")
+	expectCmd(t, red, "go")
+	expectCmd(t, blue, "go")
+
+	var seen []string
+	defer InterceptReceive(c, func(env Envelope[string], next func(Envelope[string]) error) error {
+		seen = append(seen, env.Cmd)
+		return next(env)
+	})()
+
+	Send(c, "blue")("again")
+	expectCmd(t, blue, "again")
+	if len(seen) != 1 || seen[0] != "again" {
+		t.Fatalf("Unexpected commands seen: %v", seen)
+	}
+}
+
+func TestInterceptReceiveSub(t *testing.T) {
+	c := Tagged[string]()
+	db := Sub(c, "db")
+	primary := WithTag(db, "primary").Cmd()
+	cache := WithTag(c, "cache").Cmd()
+
+	defer InterceptReceive(db, forbid("flush"))()
+
+	Send(c)("flush")
+	expectCmd(t, cache, "flush")
+	expectNoCmd(t, primary)
+}
+
+func TestInterceptListener(t *testing.T) {
+	c := Simple[string]()
+	picky := c.Cmd()
+	other := c.Cmd()
+
+	defer InterceptListener(c, picky, forbid("reload"))()
+
+	received := make(chan string, 1)
+	go func() {
+		for cmd := range picky {
+			received <- cmd
+			Ack(picky)
+		}
+	}()
+	go func() {
+		for range other {
+			Ack(other)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	replies, err := Request(c)(ctx, "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 2 || (replies[0] != errForbidden && replies[1] != errForbidden) {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+	expectNoCmd(t, received)
+
+	if _, err := Request(c)(ctx, "pause"); err != nil {
+		t.Fatal(err)
+	}
+	expectCmd(t, received, "pause")
+}
+
+func TestInterceptSendTrigger(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	detach := InterceptSend(c, forbid("pause"))
+	defer detach()
+
+	events := make(chan any)
+	stop := On[string](c, FromChan[string](events), SequencePolicy(
+		Step[string]{Cmd: "pause"},
+		Step[string]{Cmd: "resume"},
+	))
+	defer stop()
+
+	events <- nil
+
+	// The first step is rejected, the second one goes through.
+	expectCmd(t, lis, "resume")
+	expectNoCmd(t, lis)
+}
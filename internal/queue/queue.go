@@ -0,0 +1,64 @@
+// Package queue provides an unbounded queue, used to hand items from a producer that
+// must never wait to a consumer that may.
+package queue
+
+import (
+	"sync"
+)
+
+// Queue holds the items pushed and not popped yet, in order.
+type Queue[E any] struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []E
+	closed bool
+}
+
+// New creates an empty [Queue].
+func New[E any]() *Queue[E] {
+	q := &Queue[E]{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push appends the item, without ever blocking. Once the [Queue] is closed, the item
+// is discarded.
+func (q *Queue[E]) Push(item E) {
+	q.mu.Lock()
+	if !q.closed {
+		q.items = append(q.items, item)
+	}
+	q.mu.Unlock()
+
+	q.cond.Signal()
+}
+
+// Pop removes and returns the oldest item, waiting for one to be pushed if needed. It
+// returns false once the [Queue] is closed and empty.
+func (q *Queue[E]) Pop() (E, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+
+	var zero E
+	if len(q.items) == 0 {
+		return zero, false
+	}
+	item := q.items[0]
+	q.items[0] = zero
+	q.items = q.items[1:]
+	return item, true
+}
+
+// Close tells the consumers that nothing else is going to be pushed: they get the
+// items left, then [Queue.Pop] returns false.
+func (q *Queue[E]) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	q.cond.Broadcast()
+}
@@ -0,0 +1,55 @@
+// Package seen provides a bounded set of identifiers, used to discard the commands
+// that have already been delivered when they come back through another path.
+package seen
+
+import (
+	"sync"
+)
+
+// Set remembers the last identifiers it has been given.
+type Set struct {
+	mu   sync.Mutex
+	ids  map[string]struct{}
+	ring []string
+	next int
+}
+
+// New creates a [Set] remembering at most size identifiers.
+func New(size int) *Set {
+	return &Set{
+		ids:  make(map[string]struct{}, size),
+		ring: make([]string, size),
+	}
+}
+
+// Add records the identifier, returning false if it was already known.
+func (s *Set) Add(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+
+	delete(s.ids, s.ring[s.next])
+	s.ring[s.next] = id
+	s.ids[id] = struct{}{}
+	s.next = (s.next + 1) % len(s.ring)
+
+	return true
+}
+
+// IDs returns the remembered identifiers, from the oldest to the newest.
+func (s *Set) IDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.ids))
+	for i := range s.ring {
+		id := s.ring[(s.next+i)%len(s.ring)]
+		if _, ok := s.ids[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,317 @@
+package conductor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OriginReplay is the origin of the commands re-delivered by [Replay].
+const OriginReplay = "replay"
+
+const (
+	journalExt                = ".journal"
+	defaultJournalSegmentSize = 16 << 20
+)
+
+type journalEntry struct {
+	ID     string            `json:"id"`
+	Time   time.Time         `json:"time"`
+	Tags   []string          `json:"tags,omitempty"`
+	Origin string            `json:"origin,omitempty"`
+	Meta   map[string]string `json:"meta,omitempty"`
+	Cmd    []byte            `json:"cmd"`
+}
+
+// Journal is a write-ahead log of the commands sent through a [Conductor]. It is stored
+// in a directory as a sequence of segment files, each one holding a JSON line per
+// command. When the current segment grows beyond the configured size, a new one is
+// started.
+type Journal[T any] struct {
+	dir         string
+	codec       Codec[T]
+	segmentSize int64
+
+	mu   sync.Mutex
+	f    *os.File
+	seq  int
+	size int64
+}
+
+// OpenJournal opens the [Journal] stored in the given directory, creating it if needed,
+// and continues appending to its last segment. The [Codec] is used to encode the
+// commands. A segmentSize not greater than zero means 16MiB.
+func OpenJournal[T any](dir string, codec Codec[T], segmentSize int64) (*Journal[T], error) {
+	if segmentSize <= 0 {
+		segmentSize = defaultJournalSegmentSize
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	j := &Journal[T]{
+		dir:         dir,
+		codec:       codec,
+		segmentSize: segmentSize,
+	}
+
+	segments, err := j.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		j.seq = segments[len(segments)-1]
+		if err := repair(j.path(j.seq)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// Append writes the [Envelope] to the [Journal], syncing it to disk.
+func (j *Journal[T]) Append(env Envelope[T]) error {
+	data, err := j.codec.Encode(env.Cmd)
+	if err != nil {
+		return err
+	}
+
+	entry := journalEntry{
+		ID:     env.ID,
+		Time:   env.Time,
+		Origin: env.Origin,
+		Meta:   env.Meta,
+		Cmd:    data,
+	}
+	for _, tag := range env.Tags {
+		entry.Tags = append(entry.Tags, fmt.Sprint(tag))
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f == nil {
+		return os.ErrClosed
+	}
+
+	if j.size > 0 && j.size+int64(len(line)) > j.segmentSize {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.f.Write(line)
+	j.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	return j.f.Sync()
+}
+
+// Entries reads back the commands stored in the [Journal] that were sent at or after
+// the given time, in the order they were appended. A truncated last line, as left by
+// a crash, is ignored. If a segment is corrupted, the commands read before the
+// corruption are returned, along with the error.
+func (j *Journal[T]) Entries(from time.Time) ([]Envelope[T], error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	segments, err := j.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var envs []Envelope[T]
+	for _, seq := range segments {
+		read, err := j.read(seq, from)
+		envs = append(envs, read...)
+		if err != nil {
+			return envs, err
+		}
+	}
+
+	return envs, nil
+}
+
+// Close closes the current segment of the [Journal].
+func (j *Journal[T]) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.f == nil {
+		return os.ErrClosed
+	}
+
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+/* Internal functions */
+
+func (j *Journal[T]) path(seq int) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%016d%s", seq, journalExt))
+}
+
+func (j *Journal[T]) segments() ([]int, error) {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []int
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), journalExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		seq, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, seq)
+	}
+	sort.Ints(segments)
+
+	return segments, nil
+}
+
+func (j *Journal[T]) open() error {
+	f, err := os.OpenFile(j.path(j.seq), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	j.f = f
+	j.size = info.Size()
+	return nil
+}
+
+// repair truncates the segment at the given path after its last complete line, so that
+// a line torn by a crash is not continued by the next append.
+func repair(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return nil
+	}
+
+	fmt.Fprintf(logFile, "Truncating the torn last line of journal segment %s\n", path)
+	return os.Truncate(path, int64(bytes.LastIndexByte(data, '\n')+1))
+}
+
+func (j *Journal[T]) rotate() error {
+	if err := j.f.Close(); err != nil {
+		return err
+	}
+
+	j.seq++
+	return j.open()
+}
+
+func (j *Journal[T]) read(seq int, from time.Time) ([]Envelope[T], error) {
+	f, err := os.Open(j.path(seq))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var envs []Envelope[T]
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// XXX: a line without its newline was not completely written.
+			return envs, nil
+		}
+		if err != nil {
+			return envs, err
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return envs, fmt.Errorf("corrupted journal segment %s: %w", j.path(seq), err)
+		}
+
+		if entry.Time.Before(from) {
+			continue
+		}
+
+		cmd, err := j.codec.Decode(entry.Cmd)
+		if err != nil {
+			return envs, err
+		}
+
+		env := Envelope[T]{
+			ID:     entry.ID,
+			Cmd:    cmd,
+			Time:   entry.Time,
+			Origin: entry.Origin,
+			Meta:   entry.Meta,
+		}
+		for _, tag := range entry.Tags {
+			env.Tags = append(env.Tags, tag)
+		}
+		envs = append(envs, env)
+	}
+}
+
+/* Public functions */
+
+// Record appends to the [Journal] every command sent through the given [Conductor],
+// before it gets delivered. The commands re-delivered by [Replay] are not recorded
+// again. It returns a function to stop recording.
+func Record[T any](conductor Conductor[T], j *Journal[T]) func() {
+	return hubOf(conductor).addHook(func(env Envelope[T]) {
+		if env.Origin == OriginReplay {
+			return
+		}
+		if err := j.Append(env); err != nil {
+			fmt.Fprintf(logFile, "Failed to journal %s: %s\n", fmtCmd(env.Cmd), err)
+		}
+	})
+}
+
+// Replay re-delivers to the given [Conductor] the commands found in the [Journal] that
+// were sent at or after the given time, in the order they were sent, to the same tags.
+// It returns the number of commands delivered, that are the ones read before the error,
+// if any.
+func Replay[T any](conductor Conductor[T], j *Journal[T], from time.Time) (int, error) {
+	envs, err := j.Entries(from)
+	for _, env := range envs {
+		env.Origin = OriginReplay
+		Deliver(conductor, env)
+	}
+
+	return len(envs), err
+}
@@ -0,0 +1,182 @@
+package conductor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal_record(t *testing.T) {
+	dir := t.TempDir()
+	j, err := OpenJournal(dir, StringCodec[string](), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	c := Tagged[string]()
+	stop := Record(c, j)
+
+	Send(c, "first")("ciao")
+	Send(c)("miao")
+	Send(c, "second")("bau")
+	stop()
+	Send(c)("unrecorded")
+
+	envs, err := j.Entries(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(envs) != 3 {
+		t.Fatalf("Unexpected entries: %+v", envs)
+	}
+	if envs[0].Cmd != "ciao" || len(envs[0].Tags) != 1 || envs[0].Tags[0] != "first" {
+		t.Fatalf("Unexpected first entry: %+v", envs[0])
+	}
+	if envs[1].Cmd != "miao" || envs[1].Tags != nil {
+		t.Fatalf("Unexpected second entry: %+v", envs[1])
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+journalExt))
+	if len(segments) < 2 {
+		t.Fatalf("Segments did not rotate: %v", segments)
+	}
+}
+
+func TestJournal_truncated(t *testing.T) {
+	dir := t.TempDir()
+	j, err := OpenJournal(dir, StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.Append(newEnvelope("ciao", nil, "").WithMeta("trace", "abc")); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	f, err := os.OpenFile(j.path(0), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"half`)
+	f.Close()
+
+	j, err = OpenJournal(dir, StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	if err := j.Append(newEnvelope("addio", nil, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	envs, err := j.Entries(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envs) != 2 || envs[0].Cmd != "ciao" || envs[0].Meta["trace"] != "abc" || envs[1].Cmd != "addio" {
+		t.Fatalf("Unexpected entries: %+v", envs)
+	}
+}
+
+func TestJournal_corrupted(t *testing.T) {
+	dir := t.TempDir()
+	j, err := OpenJournal(dir, StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	if err := j.Append(newEnvelope("ciao", nil, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(j.path(0), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("garbage\n")
+	f.Close()
+
+	c := Simple[string]()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ready := make(chan struct{})
+	received := make(chan string, 1)
+	go func() {
+		lis := c.Cmd()
+		close(ready)
+		select {
+		case cmd := <-lis:
+			received <- cmd
+		case <-ctx.Done():
+		}
+	}()
+	<-ready
+
+	n, err := Replay(c, j, time.Time{})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if n != 1 {
+		t.Fatalf("Expected the valid prefix to be replayed, got %d commands", n)
+	}
+	select {
+	case cmd := <-received:
+		if cmd != "ciao" {
+			t.Fatalf("Unexpected command: %s", cmd)
+		}
+	case <-ctx.Done():
+		t.Fatal("Command not replayed")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	j, err := OpenJournal(t.TempDir(), StringCodec[string](), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	c := Tagged[string]()
+	Record(c, j)
+
+	Send(c, "first")("old")
+	from := time.Now()
+	Send(c, "first")("ciao")
+	Send(c, "second")("miao")
+
+	replayed := Tagged[string]()
+	Record(replayed, j)
+
+	first := setupListener(replayed, true, "first")
+	second := setupListener(replayed, false, "second")
+
+	n, err := Replay(replayed, j, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("Unexpected number of replayed commands: %d", n)
+	}
+
+	select {
+	case err := <-first:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+	<-second
+
+	envs, _ := j.Entries(time.Time{})
+	if len(envs) != 3 {
+		t.Fatalf("Replayed commands were journaled again: %+v", envs)
+	}
+}
@@ -0,0 +1,163 @@
+// Package loglevel binds a [slog.LevelVar] to a [conductor.Conductor], so that the log
+// level of a program, or of one of its subsystems, is changed at runtime by commands.
+package loglevel
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+// step is the distance between two of the levels defined by [slog].
+const step = slog.LevelInfo - slog.LevelDebug
+
+// Op is the kind of a [Change].
+type Op int
+
+const (
+	// Set sets the level of the [Change].
+	Set Op = iota
+	// Lower lowers the level by one step, e.g. from Info to Debug, so that more is
+	// logged. It never goes below Debug.
+	Lower
+	// Raise raises the level by one step, e.g. from Info to Warn, so that less is
+	// logged. It never goes above Error.
+	Raise
+	// Reset goes back to the level the [slog.LevelVar] had when it was bound.
+	Reset
+)
+
+// Change is a change of the log level, carried by a command.
+type Change struct {
+	Op Op
+	// Level is the level to set, for the Set operation.
+	Level slog.Level
+	// For, if positive, is how long the change lasts before the level is reset,
+	// overriding the Revert duration of the [Options].
+	For time.Duration
+}
+
+// Options tune a [Binding]. The zero value is usable when the commands are [Change]s.
+type Options[T any] struct {
+	// Decide tells which [Change] a command carries, if any. Defaults to use the
+	// commands that are a [Change] as is.
+	Decide func(T) (Change, bool)
+	// Revert, if positive, is how long a change lasts before the level is reset.
+	Revert time.Duration
+}
+
+// Binding is a listener of a [conductor.Conductor] that changes the level of a
+// [slog.LevelVar]. See [Bind].
+type Binding struct {
+	lv     *slog.LevelVar
+	base   slog.Level
+	mu     sync.Mutex
+	revert *time.Timer
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// Bind listens to the commands of the given [conductor.Conductor], changing the level
+// of the given [slog.LevelVar] according to the [Change] they carry, until the returned
+// [Binding] is stopped or the [conductor.Conductor] is done. To bind a subsystem, give
+// it the [conductor.Conductor] returned by [conductor.WithTag]. The requests made with
+// [conductor.Request] are answered with the resulting level.
+func Bind[T any](c conductor.Conductor[T], lv *slog.LevelVar, opts Options[T]) *Binding {
+	decide := opts.Decide
+	if decide == nil {
+		decide = func(cmd T) (Change, bool) {
+			change, ok := any(cmd).(Change)
+			return change, ok
+		}
+	}
+
+	b := &Binding{
+		lv:   lv,
+		base: lv.Level(),
+		stop: make(chan struct{}),
+	}
+
+	conductor.Spawn(c, func(lis <-chan T) {
+		defer conductor.Release(c, lis)
+		defer b.cancelRevert()
+
+		for {
+			select {
+			case <-c.Done():
+				return
+			case <-b.stop:
+				return
+			case cmd := <-lis:
+				change, ok := decide(cmd)
+				if !ok {
+					conductor.Ack(lis)
+					continue
+				}
+				revert := opts.Revert
+				if change.For > 0 {
+					revert = change.For
+				}
+				conductor.Reply(lis, b.apply(change, revert))
+			}
+		}
+	})
+
+	return b
+}
+
+func (b *Binding) apply(change Change, revert time.Duration) slog.Level {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	level := b.lv.Level()
+	switch change.Op {
+	case Set:
+		level = change.Level
+	case Lower:
+		level = max(level-step, slog.LevelDebug)
+	case Raise:
+		level = min(level+step, slog.LevelError)
+	case Reset:
+		level = b.base
+	}
+	b.lv.Set(level)
+
+	if b.revert != nil {
+		b.revert.Stop()
+		b.revert = nil
+	}
+	if revert > 0 && level != b.base {
+		var timer *time.Timer
+		timer = time.AfterFunc(revert, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			// XXX: a timer stopped too late must not undo a newer change.
+			if b.revert == timer {
+				b.lv.Set(b.base)
+				b.revert = nil
+			}
+		})
+		b.revert = timer
+	}
+
+	return level
+}
+
+func (b *Binding) cancelRevert() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.revert != nil {
+		b.revert.Stop()
+		b.revert = nil
+	}
+}
+
+// Stop stops listening to the commands. A pending revert is canceled, leaving the
+// level as it is.
+func (b *Binding) Stop() {
+	b.once.Do(func() { close(b.stop) })
+}
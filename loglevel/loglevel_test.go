@@ -0,0 +1,133 @@
+package loglevel
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+const timeout = 200 * time.Millisecond
+
+func request(t *testing.T, c conductor.Conductor[Change], change Change, tags ...any) slog.Level {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	replies, err := conductor.Request(c, tags...)(ctx, change)
+	if err != nil || len(replies) != 1 {
+		t.Fatalf("Unexpected replies: %v %v", replies, err)
+	}
+	return replies[0].(slog.Level)
+}
+
+func TestBind(t *testing.T) {
+	c := conductor.Simple[Change]()
+
+	var lv slog.LevelVar
+	b := Bind(c, &lv, Options[Change]{})
+	defer b.Stop()
+
+	if level := request(t, c, Change{Op: Lower}); level != slog.LevelDebug {
+		t.Fatalf("Unexpected level: %s", level)
+	}
+	if level := request(t, c, Change{Op: Lower}); level != slog.LevelDebug {
+		t.Fatalf("Level lowered below Debug: %s", level)
+	}
+	if level := request(t, c, Change{Op: Set, Level: slog.LevelError}); level != slog.LevelError {
+		t.Fatalf("Unexpected level: %s", level)
+	}
+	if level := request(t, c, Change{Op: Raise}); level != slog.LevelError {
+		t.Fatalf("Level raised above Error: %s", level)
+	}
+	if level := request(t, c, Change{Op: Reset}); level != slog.LevelInfo {
+		t.Fatalf("Unexpected level: %s", level)
+	}
+}
+
+func TestBindRevert(t *testing.T) {
+	c := conductor.Simple[Change]()
+
+	var lv slog.LevelVar
+	lv.Set(slog.LevelWarn)
+	b := Bind(c, &lv, Options[Change]{Revert: 20 * time.Millisecond})
+	defer b.Stop()
+
+	request(t, c, Change{Op: Lower})
+	request(t, c, Change{Op: Lower, For: 60 * time.Millisecond})
+
+	// The second change overrides the revert of the first one.
+	time.Sleep(30 * time.Millisecond)
+	if level := lv.Level(); level != slog.LevelDebug {
+		t.Fatalf("Reverted too early: %s", level)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if level := lv.Level(); level != slog.LevelWarn {
+		t.Fatalf("Not reverted: %s", level)
+	}
+}
+
+func TestBindMany(t *testing.T) {
+	c := conductor.Simple[Change]()
+
+	var first, second slog.LevelVar
+	for _, lv := range []*slog.LevelVar{&first, &second} {
+		defer Bind(c, lv, Options[Change]{}).Stop()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if replies, err := conductor.Request(c)(ctx, Change{Op: Raise}); err != nil || len(replies) != 2 {
+		t.Fatalf("Unexpected replies: %v %v", replies, err)
+	}
+
+	if first.Level() != slog.LevelWarn || second.Level() != slog.LevelWarn {
+		t.Fatalf("Unexpected levels: %s %s", first.Level(), second.Level())
+	}
+}
+
+type command string
+
+func TestBindTagged(t *testing.T) {
+	c := conductor.Tagged[command]()
+
+	var db, web slog.LevelVar
+	opts := Options[command]{
+		Decide: func(cmd command) (Change, bool) {
+			switch cmd {
+			case "verbose":
+				return Change{Op: Lower}, true
+			case "quiet":
+				return Change{Op: Raise}, true
+			default:
+				return Change{}, false
+			}
+		},
+	}
+	Bind(conductor.WithTag(c, "db"), &db, opts)
+	Bind(conductor.WithTag(c, "web"), &web, opts)
+
+	stop := conductor.Notify(c, "db")("verbose", syscall.SIGWINCH)
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if db.Level() != slog.LevelDebug || web.Level() != slog.LevelInfo {
+		t.Fatalf("Unexpected levels: db=%s web=%s", db.Level(), web.Level())
+	}
+
+	conductor.Send(c)("quiet")
+	time.Sleep(20 * time.Millisecond)
+
+	if db.Level() != slog.LevelInfo || web.Level() != slog.LevelWarn {
+		t.Fatalf("Unexpected levels: db=%s web=%s", db.Level(), web.Level())
+	}
+}
@@ -0,0 +1,244 @@
+package conductor
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// notifyQueueSize is how many commands may wait to be delivered in order by a notifier
+// using [DeliverOrdered], before it stops receiving the signals.
+const notifyQueueSize = 100
+
+// DeliveryMode tells how the commands triggered by signals are delivered. It may be given
+// among the args of [Notify] and [Escalate], and to [NotifyTable].
+type DeliveryMode int
+
+const (
+	// DeliverSync delivers the commands from the goroutine receiving the signals, so
+	// that while a command waits for the listeners to receive it, the same signals
+	// received in the meanwhile are coalesced, as [os/signal.Notify] does when its
+	// channel is full. It is the default.
+	DeliverSync DeliveryMode = iota
+	// DeliverOrdered queues the commands, one queue per notifier, to deliver them in
+	// the order the signals were received, without holding back their reception.
+	DeliverOrdered
+	// DeliverUnordered delivers each command from its own goroutine, so that none of
+	// them waits for the others and their order is not guaranteed.
+	DeliverUnordered
+)
+
+// Escalator is the return type of the [Escalate] function.
+type Escalator[T any] func(levels []Escalation[T], signals ...os.Signal) (stop func())
+
+// Escalation is a level of an escalation chain, see [Escalate].
+type Escalation[T any] struct {
+	// Cmd is the command sent when the chain reaches this level.
+	Cmd T
+	// Hook, if set, is called in place of sending Cmd, e.g. to exit the process.
+	Hook func()
+	// Within is the longest time since the previous signal for the chain to reach this
+	// level, otherwise it restarts from the first one. Zero means no limit. It is
+	// ignored for the first level.
+	Within time.Duration
+}
+
+// SignalBinding tells which command to send when a signal is received, see [NotifyTable].
+type SignalBinding[T any] struct {
+	// Signal is the signal to listen to.
+	Signal os.Signal
+	// Cmd is the command sent when the signal is received.
+	Cmd T
+	// Tags are the tags the command is sent to, in the same way as [Send] does. The
+	// command is broadcast if there are none.
+	Tags []any
+}
+
+// dispatcher runs the deliveries of a notifier according to its [DeliveryMode].
+type dispatcher struct {
+	mode    DeliveryMode
+	stop    <-chan struct{}
+	done    <-chan struct{}
+	queue   chan func()
+	mu      sync.Mutex
+	closed  bool
+	sending sync.WaitGroup
+}
+
+// newDispatcher creates a dispatcher giving up on the deliveries waiting to be queued
+// once stop or done is closed.
+func newDispatcher(mode DeliveryMode, stop, done <-chan struct{}) *dispatcher {
+	d := &dispatcher{
+		mode: mode,
+		stop: stop,
+		done: done,
+	}
+	if mode == DeliverOrdered {
+		d.queue = make(chan func(), notifyQueueSize)
+		go func() {
+			for deliver := range d.queue {
+				deliver()
+			}
+		}()
+	}
+	return d
+}
+
+// run runs the delivery, unless the dispatcher is closed.
+func (d *dispatcher) run(deliver func()) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+
+	switch d.mode {
+	case DeliverOrdered:
+		d.sending.Add(1)
+		d.mu.Unlock()
+		defer d.sending.Done()
+
+		// XXX: a full queue is waited for without holding the lock, and only
+		// until the notifier is stopped, so that stopping it never blocks.
+		select {
+		case d.queue <- deliver:
+		case <-d.stop:
+		case <-d.done:
+		}
+	case DeliverUnordered:
+		d.mu.Unlock()
+		go deliver()
+	default:
+		defer d.mu.Unlock()
+		deliver()
+	}
+}
+
+// close stops accepting deliveries, the queued ones still being run.
+func (d *dispatcher) close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	if d.queue != nil {
+		d.sending.Wait()
+		close(d.queue)
+	}
+}
+
+// notifyOptions separates the [DeliveryMode] from the tags in the args of a notifier.
+func notifyOptions(args []any) ([]any, DeliveryMode) {
+	var tags []any
+	mode := DeliverSync
+	for _, arg := range args {
+		if m, ok := arg.(DeliveryMode); ok {
+			mode = m
+			continue
+		}
+		tags = append(tags, arg)
+	}
+	return tags, mode
+}
+
+// listen calls handle for each of the given signals received, until the [Conductor] is
+// done or the returned function is called. The function returned by handle, if any, is
+// then run according to the given [DeliveryMode].
+func listen[T any](conductor Conductor[T], signals []os.Signal, mode DeliveryMode, handle func(os.Signal) func()) func() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	stop := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(stop)
+		})
+	}
+
+	d := newDispatcher(mode, stop, conductor.Done())
+	go func() {
+		defer cancel()
+		defer d.close()
+
+		for {
+			select {
+			case <-conductor.Done():
+				return
+			case <-stop:
+				return
+			case sig := <-ch:
+				if deliver := handle(sig); deliver != nil {
+					d.run(deliver)
+				}
+			}
+		}
+	}()
+
+	return cancel
+}
+
+// Escalate may be used on a [Conductor] to create a function that, instead of sending
+// always the same command as [Notify] does, climbs a chain of levels each time one of
+// the signals is received, e.g. a first SIGINT drains, a second one within 5 seconds
+// stops and a third one exits the process. Once the last level is reached, it is
+// repeated for the following signals. The optional variadic args are handled as by
+// [Notify]: the levels are climbed as the signals are received, whatever the
+// [DeliveryMode] of their commands. The returned function stops listening to the signals.
+func Escalate[T any](conductor Conductor[T], args ...any) Escalator[T] {
+	tags, mode := notifyOptions(args)
+	send := Send(conductor, tags...)
+
+	return func(levels []Escalation[T], signals ...os.Signal) func() {
+		var next int
+		var last time.Time
+		return listen(conductor, signals, mode, func(os.Signal) func() {
+			if len(levels) == 0 {
+				return nil
+			}
+
+			now := time.Now()
+			if next >= len(levels) {
+				next = len(levels) - 1
+			}
+			if within := levels[next].Within; next > 0 && within > 0 && now.Sub(last) > within {
+				next = 0
+			}
+
+			level := levels[next]
+			next, last = next+1, now
+			if level.Hook != nil {
+				return level.Hook
+			}
+			return func() {
+				send(level.Cmd)
+			}
+		})
+	}
+}
+
+// NotifyTable registers the [Conductor] to all the signals of the given table at once,
+// sending the command bound to each signal, to its tags, when it is received, according
+// to the given [DeliveryMode]. More commands may be bound to the same signal. The
+// returned function stops listening to the signals.
+func NotifyTable[T any](conductor Conductor[T], mode DeliveryMode, table ...SignalBinding[T]) func() {
+	signals := make([]os.Signal, 0, len(table))
+	for _, binding := range table {
+		signals = append(signals, binding.Signal)
+	}
+
+	return listen(conductor, signals, mode, func(sig os.Signal) func() {
+		return func() {
+			for _, binding := range table {
+				if binding.Signal == sig {
+					Send(conductor, binding.Tags...)(binding.Cmd)
+				}
+			}
+		}
+	})
+}
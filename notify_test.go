@@ -0,0 +1,215 @@
+package conductor
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func raise(t *testing.T, sig os.Signal) {
+	t.Helper()
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(sig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifyStop(t *testing.T) {
+	c := Tagged[string]()
+	lis := c.Cmd()
+
+	stop := Notify(c)("ciao", syscall.SIGWINCH)
+
+	raise(t, syscall.SIGWINCH)
+	expectCmd(t, lis, "ciao")
+
+	stop()
+	stop()
+
+	// SIGWINCH is ignored by default, so it is safe to raise it when not notified.
+	raise(t, syscall.SIGWINCH)
+	expectNoCmd(t, lis)
+}
+
+func TestEscalate(t *testing.T) {
+	c := Tagged[string]()
+	lis := WithTag(c, "workers").Cmd()
+	other := WithTag(c, "other").Cmd()
+
+	exited := make(chan struct{}, 1)
+	stop := Escalate(c, "workers")([]Escalation[string]{
+		{Cmd: "drain"},
+		{Cmd: "stop", Within: failureTimeout},
+		{Hook: func() { exited <- struct{}{} }, Within: failureTimeout},
+	}, syscall.SIGWINCH)
+	defer stop()
+
+	raise(t, syscall.SIGWINCH)
+	expectCmd(t, lis, "drain")
+
+	// Too late for the second level, so the chain restarts.
+	time.Sleep(2 * failureTimeout)
+	raise(t, syscall.SIGWINCH)
+	expectCmd(t, lis, "drain")
+
+	raise(t, syscall.SIGWINCH)
+	expectCmd(t, lis, "stop")
+
+	raise(t, syscall.SIGWINCH)
+	select {
+	case <-exited:
+	case <-time.After(failureTimeout):
+		t.Fatal("Hook not called")
+	}
+	expectNoCmd(t, lis)
+	expectNoCmd(t, other)
+}
+
+func TestNotifyTable(t *testing.T) {
+	// Keep the default action, that would terminate the process, from happening.
+	guard := make(chan os.Signal, 10)
+	signal.Notify(guard, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(guard)
+
+	c := Tagged[string]()
+	db := WithTag(c, "db").Cmd()
+	web := WithTag(c, "web").Cmd()
+
+	stop := NotifyTable[string](c, DeliverSync,
+		SignalBinding[string]{Signal: syscall.SIGUSR1, Cmd: "pause", Tags: []any{"db"}},
+		SignalBinding[string]{Signal: syscall.SIGUSR2, Cmd: "resume"},
+	)
+	defer stop()
+
+	raise(t, syscall.SIGUSR1)
+	expectCmd(t, db, "pause")
+	expectNoCmd(t, web)
+
+	raise(t, syscall.SIGUSR2)
+	expectCmd(t, db, "resume")
+	expectCmd(t, web, "resume")
+}
+
+func burst(t *testing.T, sig os.Signal, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		raise(t, sig)
+		// XXX: the same signal raised again before being handled is coalesced by
+		// the operating system, so we leave some time in between.
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNotifyDeliveryModes(t *testing.T) {
+	for _, mode := range []DeliveryMode{DeliverSync, DeliverOrdered, DeliverUnordered} {
+		for _, c := range []Conductor[string]{Simple[string](), Tagged[string]()} {
+			lis := c.Cmd()
+			stop := Notify(c, mode)("ciao", syscall.SIGWINCH)
+
+			burst(t, syscall.SIGWINCH, 5)
+			for i := 0; i < 5; i++ {
+				expectCmd(t, lis, "ciao")
+			}
+			expectNoCmd(t, lis)
+
+			stop()
+		}
+	}
+}
+
+func TestEscalateOrderedBurst(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	// The listener is full, so that the commands have to wait to be delivered.
+	for i := 0; i < cmdBufSize; i++ {
+		Send(c)("fill")
+	}
+
+	levels := []Escalation[string]{{Cmd: "first"}, {Cmd: "second"}, {Cmd: "third"}}
+	stop := Escalate(c, DeliverOrdered)(levels, syscall.SIGWINCH)
+	defer stop()
+
+	burst(t, syscall.SIGWINCH, 3)
+
+	for i := 0; i < cmdBufSize; i++ {
+		expectCmd(t, lis, "fill")
+	}
+	for _, level := range levels {
+		expectCmd(t, lis, level.Cmd)
+	}
+}
+
+func TestNotifySyncBurstCoalesced(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	for i := 0; i < cmdBufSize; i++ {
+		Send(c)("fill")
+	}
+
+	stop := Notify(c, DeliverSync)("ciao", syscall.SIGWINCH)
+	defer stop()
+
+	// The first signal blocks the notifier, the second one waits in its channel and
+	// the others are lost.
+	burst(t, syscall.SIGWINCH, 5)
+
+	var received int
+	for i := 0; i < cmdBufSize; i++ {
+		expectCmd(t, lis, "fill")
+	}
+loop:
+	for {
+		select {
+		case <-lis:
+			received++
+		case <-time.After(successTimeout):
+			break loop
+		}
+	}
+
+	// XXX: the second one may have been lost too, if the burst was over before the
+	// notifier got the first one.
+	if received < 1 || received > 2 {
+		t.Fatalf("Unexpected commands received: %d", received)
+	}
+}
+
+func TestNotifyOrderedStopWithFullQueue(t *testing.T) {
+	c := Simple[string]()
+	_ = c.Cmd()
+
+	stop := make(chan struct{})
+	d := newDispatcher(DeliverOrdered, stop, c.Done())
+
+	// The listener never receives, so the queue fills up and the next delivery waits.
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		defer d.close()
+
+		for i := 0; i < cmdBufSize+notifyQueueSize+2; i++ {
+			d.run(func() { Send(c)("ciao") })
+		}
+	}()
+
+	select {
+	case <-returned:
+		t.Fatal("Deliveries not held back")
+	case <-time.After(successTimeout):
+	}
+
+	close(stop)
+
+	select {
+	case <-returned:
+	case <-time.After(failureTimeout):
+		t.Fatal("Notifier stuck on its full queue")
+	}
+}
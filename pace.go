@@ -0,0 +1,218 @@
+package conductor
+
+import (
+	"sync"
+	"time"
+)
+
+// Edge tells which commands of an interval a throttled listener receives, see
+// [Throttle]. The edges may be combined.
+type Edge int
+
+const (
+	// Leading delivers the first command of the interval, right away.
+	Leading Edge = 1 << iota
+	// Trailing delivers the last command of the interval, once it is over.
+	Trailing
+)
+
+// pacer holds back the commands delivered to a listener, to deliver them later or not
+// at all.
+type pacer[T any] struct {
+	owner   *simple[T]
+	ch      chan T
+	gone    <-chan struct{}
+	done    <-chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending *Envelope[T]
+}
+
+func newPacer[T any](conductor Conductor[T], lis <-chan T) *pacer[T] {
+	// XXX: the listener is waited for to leave before looking for it, so that it
+	// cannot leave unnoticed in between.
+	gone := hubOf(conductor).gone(lis)
+
+	var owners []*simple[T]
+	switch c := any(unwrap(conductor)).(type) {
+	case *simple[T]:
+		owners = []*simple[T]{c}
+	case *tagged[T]:
+		owners = c.all()
+	}
+
+	for _, owner := range owners {
+		for _, ch := range owner.channels() {
+			if (<-chan T)(ch) == lis {
+				return &pacer[T]{
+					owner: owner,
+					ch:    ch,
+					gone:  gone,
+					done:  conductor.Done(),
+					stop:  make(chan struct{}),
+				}
+			}
+		}
+	}
+	panic("not a listener of the conductor")
+}
+
+// hold replaces the command held back, if any, with the given one.
+func (p *pacer[T]) hold(env Envelope[T]) {
+	p.pending = &env
+}
+
+// flush delivers the command held back, if any, and tells whether there was one. It
+// must be called with the lock held, that is released while delivering. As for the
+// other commands, a listener too slow to receive it is evicted (see [EvictSlow]), and
+// it is given up once the listener leaves.
+func (p *pacer[T]) flush() bool {
+	if p.pending == nil {
+		return false
+	}
+	env := *p.pending
+	p.pending = nil
+
+	p.mu.Unlock()
+	defer p.mu.Lock()
+
+	var timeout <-chan time.Time
+	after := time.Duration(p.owner.hub.evictAfter.Load())
+	if after > 0 {
+		timer := time.NewTimer(after)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.ch <- env.Cmd:
+		p.owner.hub.pushed(p.ch, env.Origin == OriginPolicy)
+	case <-timeout:
+		p.owner.evict(p.ch, after)
+	case <-p.gone:
+	case <-p.done:
+	case <-p.stop:
+	}
+	return true
+}
+
+// schedule calls fn, with the lock held, once the given interval is over, in place of
+// what was scheduled before.
+func (p *pacer[T]) schedule(d time.Duration, fn func()) {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		// XXX: a timer stopped too late may still get here, after a newer one
+		// was scheduled.
+		if p.timer != timer {
+			return
+		}
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+		fn()
+	})
+	p.timer = timer
+}
+
+// attach attaches the given interceptor to the listener, returning the function that
+// detaches it and discards the command held back.
+func (p *pacer[T]) attach(conductor Conductor[T], lis <-chan T, ic Interceptor[T]) func() {
+	detach := InterceptListener(conductor, lis, ic)
+	return func() {
+		p.once.Do(func() {
+			detach()
+			close(p.stop)
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if p.timer != nil {
+				p.timer.Stop()
+			}
+			p.pending = nil
+		})
+	}
+}
+
+/* Public functions */
+
+// Debounce makes the given listener, i.e. a channel returned by [Conductor.Cmd],
+// receive a command only after no other one was delivered to it for the given quiet
+// period, and then only the last one, e.g. to reload once after a burst of file
+// changes. The commands delivered late skip the interceptors attached to the listener
+// after the debouncing, and are discarded if the [Conductor] is done or the listener
+// leaves meanwhile; a listener too slow to receive them is evicted, as set with
+// [EvictSlow]. It works with any kind of [Conductor], and the returned function stops
+// debouncing.
+func Debounce[T any](conductor Conductor[T], lis <-chan T, quiet time.Duration) func() {
+	p := newPacer(conductor, lis)
+
+	return p.attach(conductor, lis, func(env Envelope[T], _ func(Envelope[T]) error) error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.hold(env)
+		p.schedule(quiet, func() {
+			p.flush()
+		})
+		return nil
+	})
+}
+
+// Throttle makes the given listener, i.e. a channel returned by [Conductor.Cmd],
+// receive at most one command per interval: the first one of the interval, right
+// away, with the [Leading] edge, and the last one, once the interval is over, with the
+// [Trailing] edge. With both edges, the command delivered at the end of an interval
+// opens the next one. Without any, it behaves as with the [Leading] edge. As for
+// [Debounce], the commands delivered late skip the interceptors attached after the
+// throttling. The returned function stops throttling.
+func Throttle[T any](conductor Conductor[T], lis <-chan T, interval time.Duration, edge Edge) func() {
+	if edge&(Leading|Trailing) == 0 {
+		edge = Leading
+	}
+
+	p := newPacer(conductor, lis)
+	var open bool
+
+	var tick func()
+	tick = func() {
+		if edge&Trailing != 0 && p.flush() {
+			p.schedule(interval, tick)
+			return
+		}
+		open = false
+	}
+
+	return p.attach(conductor, lis, func(env Envelope[T], next func(Envelope[T]) error) error {
+		p.mu.Lock()
+
+		if open {
+			if edge&Trailing != 0 {
+				p.hold(env)
+			}
+			p.mu.Unlock()
+			return nil
+		}
+
+		open = true
+		p.schedule(interval, tick)
+		if edge&Leading == 0 {
+			p.hold(env)
+			p.mu.Unlock()
+			return nil
+		}
+
+		p.mu.Unlock()
+		return next(env)
+	})
+}
@@ -0,0 +1,122 @@
+package conductor
+
+import (
+	"testing"
+	"time"
+)
+
+const paceInterval = 30 * time.Millisecond
+
+func TestDebounce(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+	other := c.Cmd()
+
+	stop := Debounce(c, lis, paceInterval)
+
+	for _, cmd := range []string{"a", "b", "c"} {
+		Send(c)(cmd)
+		expectCmd(t, other, cmd)
+		time.Sleep(paceInterval / 3)
+	}
+	select {
+	case cmd := <-lis:
+		t.Fatalf("Command %s delivered before the quiet period", cmd)
+	default:
+	}
+
+	expectCmd(t, lis, "c")
+	expectNoCmd(t, lis)
+
+	stop()
+	Send(c)("d")
+	expectCmd(t, lis, "d")
+}
+
+func TestThrottle(t *testing.T) {
+	for name, tc := range map[string]struct {
+		edge     Edge
+		expected []string
+	}{
+		"leading":  {edge: Leading, expected: []string{"a"}},
+		"trailing": {edge: Trailing, expected: []string{"c"}},
+		"both":     {edge: Leading | Trailing, expected: []string{"a", "c"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := Tagged[string]()
+			lis := WithTag(c, "reload").Cmd()
+			defer Throttle(c, lis, paceInterval, tc.edge)()
+
+			for _, cmd := range []string{"a", "b", "c"} {
+				Send(c, "reload")(cmd)
+			}
+
+			for _, cmd := range tc.expected {
+				expectCmd(t, lis, cmd)
+			}
+			expectNoCmd(t, lis)
+
+			// Once the interval is over, the next command opens a new one.
+			time.Sleep(paceInterval)
+			Send(c, "reload")("d")
+			expectCmd(t, lis, "d")
+		})
+	}
+}
+
+func TestDebounceEvictsSlow(t *testing.T) {
+	c := Simple[string]()
+	EvictSlow[string](c, 10*time.Millisecond)
+	lis := c.Cmd()
+
+	defer Debounce[string](c, lis, time.Millisecond)()
+
+	// The listener never reads, so the commands delivered late fill its buffer, and
+	// then it gets evicted.
+	for i := 0; i <= cmdBufSize; i++ {
+		Send(c)("ciao")
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	deadline := time.After(failureTimeout)
+	for len(Listeners[string](c)[""]) > 0 {
+		select {
+		case <-deadline:
+			t.Fatal("Slow listener not evicted")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestPacerGivesUpOnRelease(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+	for i := 0; i < cmdBufSize; i++ {
+		Send(c)("fill")
+	}
+
+	p := newPacer[string](c, lis)
+	p.hold(newEnvelope("ciao", nil, ""))
+
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.flush()
+	}()
+
+	select {
+	case <-flushed:
+		t.Fatal("Command pushed to a full listener")
+	case <-time.After(successTimeout):
+	}
+
+	Release[string](c, lis)
+
+	select {
+	case <-flushed:
+	case <-time.After(failureTimeout):
+		t.Fatal("Command still pushed to a released listener")
+	}
+}
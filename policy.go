@@ -1,5 +1,12 @@
 package conductor
 
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
 // Policy is invoked when the [Conductor] gets cancelled, in the sense of [context] (i.e.
 // being it a [context.Context], it can be canceled in the usual way). When this happens,
 // a Policy is a way to decide what to do with all the listeners loaded in a [Conductor].
@@ -8,6 +15,64 @@ type Policy[T any] interface {
 	Decide(args ...any) (T, bool)
 }
 
+// CausePolicy is a [Policy] that also wants to know why the [Conductor] got canceled.
+// When attached with [Conductor.WithContextPolicy], DecideCause is invoked in place of
+// Decide, with the error of the context and its cause, as returned by [context.Cause].
+type CausePolicy[T any] interface {
+	Policy[T]
+	// DecideCause gets invoked when the [Conductor] is canceled.
+	DecideCause(err, cause error, args ...any) (T, bool)
+}
+
+// Step is one of the commands fired, in order, by a [MultiPolicy].
+type Step[T any] struct {
+	// Cmd is the command to fire.
+	Cmd T
+	// Delay is waited before firing the command.
+	Delay time.Duration
+	// AckTimeout, if positive, is the longest time waited for the listeners to
+	// acknowledge the command, using [Ack] or [Reply], before moving to the next step.
+	AckTimeout time.Duration
+}
+
+// MultiPolicy is a [Policy] that may fire a sequence of commands to each listener,
+// e.g. to pause, flush and then stop. When attached with [Conductor.WithContextPolicy],
+// DecideSteps is invoked in place of Decide and DecideCause, with the same arguments as
+// the latter, and the steps are delivered in order.
+type MultiPolicy[T any] interface {
+	Policy[T]
+	// DecideSteps gets invoked when the [Conductor] is canceled.
+	DecideSteps(err, cause error, args ...any) ([]Step[T], bool)
+}
+
+// decideSteps asks the [Policy] for the steps to fire, wrapping a single command in a
+// single step if it is not a [MultiPolicy].
+func decideSteps[T any](policy Policy[T], err, cause error, args ...any) ([]Step[T], bool) {
+	if p, ok := policy.(MultiPolicy[T]); ok {
+		return p.DecideSteps(err, cause, args...)
+	}
+	if cmd, ok := decide(policy, err, cause, args...); ok {
+		return []Step[T]{{Cmd: cmd}}, true
+	}
+	return nil, false
+}
+
+// firstStep adapts the result of DecideSteps to the one of DecideCause.
+func firstStep[T any](steps []Step[T], ok bool) (zero T, falsy bool) {
+	if !ok || len(steps) == 0 {
+		return zero, false
+	}
+	return steps[0].Cmd, true
+}
+
+// decide asks the [Policy], passing it the cancellation cause if it is interested.
+func decide[T any](policy Policy[T], err, cause error, args ...any) (T, bool) {
+	if p, ok := policy.(CausePolicy[T]); ok {
+		return p.DecideCause(err, cause, args...)
+	}
+	return policy.Decide(args...)
+}
+
 type constantPolicy[T any] struct {
 	cmd T
 }
@@ -45,3 +110,244 @@ func SetPolicy[T any](mapping map[any]T) Policy[T] {
 		mapping: mapping,
 	}
 }
+
+type funcPolicy[T any] struct {
+	fn func(args ...any) (T, bool)
+}
+
+func (p *funcPolicy[T]) Decide(args ...any) (T, bool) {
+	return p.fn(args...)
+}
+
+// FuncPolicy adapts a function to a [Policy].
+func FuncPolicy[T any](fn func(args ...any) (T, bool)) Policy[T] {
+	return &funcPolicy[T]{
+		fn: fn,
+	}
+}
+
+type firstOfPolicy[T any] struct {
+	policies []Policy[T]
+}
+
+func (p *firstOfPolicy[T]) Decide(args ...any) (T, bool) {
+	return p.DecideCause(nil, nil, args...)
+}
+
+func (p *firstOfPolicy[T]) DecideCause(err, cause error, args ...any) (T, bool) {
+	return firstStep(p.DecideSteps(err, cause, args...))
+}
+
+func (p *firstOfPolicy[T]) DecideSteps(err, cause error, args ...any) ([]Step[T], bool) {
+	for _, policy := range p.policies {
+		if steps, ok := decideSteps(policy, err, cause, args...); ok {
+			return steps, true
+		}
+	}
+
+	return nil, false
+}
+
+// FirstOf creates a [Policy] that asks the given policies in order, and fires the
+// command of the first one that decides to fire.
+func FirstOf[T any](policies ...Policy[T]) Policy[T] {
+	return &firstOfPolicy[T]{
+		policies: policies,
+	}
+}
+
+// Chain is another name of [FirstOf]: the given policies are chained, each one
+// falling through to the next when it does not decide to fire.
+func Chain[T any](policies ...Policy[T]) Policy[T] {
+	return FirstOf(policies...)
+}
+
+// Default creates a [Policy] that fires the given command whenever the wrapped
+// [Policy] does not decide to fire, e.g. for the tags missing from a [SetPolicy].
+func Default[T any](policy Policy[T], cmd T) Policy[T] {
+	return FirstOf(policy, ConstantPolicy(cmd))
+}
+
+type exceptPolicy[T any] struct {
+	policy Policy[T]
+	except map[any]struct{}
+}
+
+func (p *exceptPolicy[T]) Decide(tags ...any) (T, bool) {
+	return p.DecideCause(nil, nil, tags...)
+}
+
+func (p *exceptPolicy[T]) DecideCause(err, cause error, tags ...any) (T, bool) {
+	return firstStep(p.DecideSteps(err, cause, tags...))
+}
+
+func (p *exceptPolicy[T]) DecideSteps(err, cause error, tags ...any) ([]Step[T], bool) {
+	for _, tag := range tags {
+		if _, ok := p.except[tag]; ok {
+			return nil, false
+		}
+	}
+
+	return decideSteps(p.policy, err, cause, tags...)
+}
+
+// ExceptTags creates a [Policy] that never fires for the given tags, and defers to the
+// wrapped [Policy] for all the others.
+func ExceptTags[T any](policy Policy[T], tags ...any) Policy[T] {
+	except := make(map[any]struct{}, len(tags))
+	for _, tag := range tags {
+		except[tag] = struct{}{}
+	}
+
+	return &exceptPolicy[T]{
+		policy: policy,
+		except: except,
+	}
+}
+
+type matchPolicy[T any] struct {
+	patterns []string
+	mapping  map[string]T
+	match    func(pattern, tag string) bool
+}
+
+func (p *matchPolicy[T]) Decide(tags ...any) (zero T, falsy bool) {
+	for _, tag := range tags {
+		name, ok := tag.(string)
+		if !ok || tag == defaultTag {
+			continue
+		}
+		for _, pattern := range p.patterns {
+			if p.match(pattern, name) {
+				return p.mapping[pattern], true
+			}
+		}
+	}
+
+	return zero, false
+}
+
+func newMatchPolicy[T any](mapping map[string]T, match func(pattern, tag string) bool) Policy[T] {
+	patterns := make([]string, 0, len(mapping))
+	for pattern := range mapping {
+		patterns = append(patterns, pattern)
+	}
+	// XXX: the longest, i.e. the most specific, pattern wins.
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	return &matchPolicy[T]{
+		patterns: patterns,
+		mapping:  mapping,
+		match:    match,
+	}
+}
+
+// PrefixPolicy creates a [Policy] that fires the command associated to the longest
+// prefix of the tag found in the provided map. As [SetPolicy], it is to be used only
+// with a [Tagged] [Conductor].
+func PrefixPolicy[T any](mapping map[string]T) Policy[T] {
+	return newMatchPolicy(mapping, func(prefix, tag string) bool {
+		return strings.HasPrefix(tag, prefix)
+	})
+}
+
+// PatternPolicy creates a [Policy] that fires the command associated to the pattern,
+// in the syntax of [path.Match], that matches the tag. When many patterns match, the
+// longest wins. As [SetPolicy], it is to be used only with a [Tagged] [Conductor].
+func PatternPolicy[T any](mapping map[string]T) Policy[T] {
+	return newMatchPolicy(mapping, func(pattern, tag string) bool {
+		ok, _ := path.Match(pattern, tag)
+		return ok
+	})
+}
+
+type causePolicy[T any] struct {
+	choose func(err, cause error) Policy[T]
+}
+
+func (p *causePolicy[T]) Decide(args ...any) (T, bool) {
+	return p.DecideCause(nil, nil, args...)
+}
+
+func (p *causePolicy[T]) DecideCause(err, cause error, args ...any) (T, bool) {
+	return firstStep(p.DecideSteps(err, cause, args...))
+}
+
+func (p *causePolicy[T]) DecideSteps(err, cause error, args ...any) ([]Step[T], bool) {
+	policy := p.choose(err, cause)
+	if policy == nil {
+		return nil, false
+	}
+
+	return decideSteps(policy, err, cause, args...)
+}
+
+// OnCause creates a [CausePolicy] that uses the given function to choose the [Policy]
+// to apply, depending on why the [Conductor] got canceled: err is the error of the
+// context, i.e. [context.Canceled] or [context.DeadlineExceeded], while cause is the
+// one given to the function returned by [WithCancelCause] and its siblings. Returning
+// nil means not to fire any command.
+func OnCause[T any](choose func(err, cause error) Policy[T]) Policy[T] {
+	return &causePolicy[T]{
+		choose: choose,
+	}
+}
+
+type sequencePolicy[T any] struct {
+	steps []Step[T]
+}
+
+func (p *sequencePolicy[T]) Decide(args ...any) (T, bool) {
+	return p.DecideCause(nil, nil, args...)
+}
+
+func (p *sequencePolicy[T]) DecideCause(err, cause error, args ...any) (T, bool) {
+	return firstStep(p.DecideSteps(err, cause, args...))
+}
+
+func (p *sequencePolicy[T]) DecideSteps(error, error, ...any) ([]Step[T], bool) {
+	return p.steps, true
+}
+
+// SequencePolicy creates a [MultiPolicy] that fires the given steps, in order, to all
+// the listeners.
+func SequencePolicy[T any](steps ...Step[T]) Policy[T] {
+	return &sequencePolicy[T]{
+		steps: steps,
+	}
+}
+
+type setSequencePolicy[T any] struct {
+	mapping map[any][]Step[T]
+}
+
+func (p *setSequencePolicy[T]) Decide(tags ...any) (T, bool) {
+	return p.DecideCause(nil, nil, tags...)
+}
+
+func (p *setSequencePolicy[T]) DecideCause(err, cause error, tags ...any) (T, bool) {
+	return firstStep(p.DecideSteps(err, cause, tags...))
+}
+
+func (p *setSequencePolicy[T]) DecideSteps(_, _ error, tags ...any) ([]Step[T], bool) {
+	for _, tag := range tags {
+		if steps, ok := p.mapping[tag]; ok {
+			return steps, true
+		}
+	}
+
+	return nil, false
+}
+
+// SetSequencePolicy creates a [MultiPolicy] that works as [SetPolicy], but fires the
+// sequence of steps found at the associated identifier in the map.
+func SetSequencePolicy[T any](mapping map[any][]Step[T]) Policy[T] {
+	return &setSequencePolicy[T]{
+		mapping: mapping,
+	}
+}
@@ -2,6 +2,7 @@ package conductor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -132,3 +133,230 @@ loop:
 	// miao
 	// bau
 }
+
+func assertDecision(t *testing.T, p Policy[string], tag any, want string, wantOk bool) {
+	t.Helper()
+
+	cmd, ok := p.Decide(tag)
+	if ok != wantOk || cmd != want {
+		t.Fatalf("[%v] unexpected decision: %q, %v", tag, cmd, ok)
+	}
+}
+
+func Test_funcPolicy(t *testing.T) {
+	p := FuncPolicy(func(args ...any) (string, bool) {
+		return "ciao", len(args) > 0 && args[0] == "first"
+	})
+
+	assertDecision(t, p, "first", "ciao", true)
+	assertDecision(t, p, "second", "ciao", false)
+}
+
+func Test_firstOfPolicy(t *testing.T) {
+	p := FirstOf(
+		SetPolicy(map[any]string{"first": "ciao"}),
+		SetPolicy(map[any]string{"first": "miao", "second": "bau"}),
+	)
+
+	assertDecision(t, p, "first", "ciao", true)
+	assertDecision(t, p, "second", "bau", true)
+	assertDecision(t, p, "third", "", false)
+}
+
+func Test_chainPolicy(t *testing.T) {
+	p := Chain(
+		ExceptTags(ConstantPolicy("ciao"), "second", "third"),
+		SetPolicy(map[any]string{"second": "bau"}),
+	)
+
+	assertDecision(t, p, "first", "ciao", true)
+	assertDecision(t, p, "second", "bau", true)
+	assertDecision(t, p, "third", "", false)
+}
+
+func Test_defaultPolicy(t *testing.T) {
+	p := Default(SetPolicy(map[any]string{"first": "ciao"}), "miao")
+
+	assertDecision(t, p, "first", "ciao", true)
+	assertDecision(t, p, "second", "miao", true)
+}
+
+func Test_exceptPolicy(t *testing.T) {
+	p := ExceptTags(ConstantPolicy("ciao"), "second")
+
+	assertDecision(t, p, "first", "ciao", true)
+	assertDecision(t, p, "second", "", false)
+}
+
+func Test_prefixPolicy(t *testing.T) {
+	p := PrefixPolicy(map[string]string{
+		"db":         "ciao",
+		"db.primary": "miao",
+	})
+
+	assertDecision(t, p, "db.replica", "ciao", true)
+	assertDecision(t, p, "db.primary.0", "miao", true)
+	assertDecision(t, p, "web", "", false)
+	assertDecision(t, p, defaultTag, "", false)
+}
+
+func Test_patternPolicy(t *testing.T) {
+	p := PatternPolicy(map[string]string{
+		"worker-*":   "ciao",
+		"worker-?-a": "miao",
+	})
+
+	assertDecision(t, p, "worker-12", "ciao", true)
+	assertDecision(t, p, "worker-1-a", "miao", true)
+	assertDecision(t, p, "web", "", false)
+}
+
+func ExampleDefault() {
+	tagged, cancel := WithCancel(Tagged[string]())
+	tagged.WithContextPolicy(Default(
+		ExceptTags(PrefixPolicy(map[string]string{"db": "ciao"}), "db.audit"),
+		"miao",
+	))
+
+	lisPrimary := WithTag(tagged, "db.primary").Cmd()
+	lisAudit := WithTag(tagged, "db.audit").Cmd()
+
+	go cancel()
+
+	fmt.Println(<-lisPrimary)
+	fmt.Println(<-lisAudit)
+	// Output:
+	// ciao
+	// miao
+}
+
+func Test_causePolicy(t *testing.T) {
+	restart := errors.New("restart")
+	policy := OnCause(func(err, cause error) Policy[string] {
+		switch {
+		case errors.Is(cause, restart):
+			return ConstantPolicy("restart")
+		case errors.Is(err, context.DeadlineExceeded):
+			return ConstantPolicy("drain")
+		default:
+			return ConstantPolicy("stop")
+		}
+	})
+
+	for _, tc := range []struct {
+		name   string
+		cancel func(Conductor[string]) (Conductor[string], func())
+		want   string
+	}{
+		{
+			name: "canceled",
+			cancel: func(c Conductor[string]) (Conductor[string], func()) {
+				c, cancel := WithCancel(c)
+				return c, cancel
+			},
+			want: "stop",
+		},
+		{
+			name: "deadline",
+			cancel: func(c Conductor[string]) (Conductor[string], func()) {
+				c, cancel := WithTimeout(c, time.Millisecond)
+				return c, func() {
+					// XXX: let the deadline expire first.
+					time.Sleep(5 * time.Millisecond)
+					cancel()
+				}
+			},
+			want: "drain",
+		},
+		{
+			name: "cause",
+			cancel: func(c Conductor[string]) (Conductor[string], func()) {
+				c, cancel := WithCancelCause(c)
+				return c, func() { cancel(restart) }
+			},
+			want: "restart",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, cancel := tc.cancel(Tagged[string]())
+			c.WithContextPolicy(ExceptTags(policy, "second"))
+
+			first := WithTag(c, "first").Cmd()
+			second := WithTag(c, "second").Cmd()
+
+			go cancel()
+
+			select {
+			case cmd := <-first:
+				if cmd != tc.want {
+					t.Fatalf("Unexpected cmd: %s", cmd)
+				}
+			case cmd := <-second:
+				t.Fatalf("second received: %s", cmd)
+			case <-time.After(failureTimeout):
+				t.Fatal("Timeout")
+			}
+		})
+	}
+}
+
+func Test_sequencePolicy(t *testing.T) {
+	c, cancel := WithCancel(Simple[string]())
+	c.WithContextPolicy(SequencePolicy(
+		Step[string]{Cmd: "pause"},
+		Step[string]{Cmd: "flush", AckTimeout: time.Second},
+		Step[string]{Cmd: "stop", Delay: 5 * time.Millisecond},
+	))
+
+	lis := c.Cmd()
+
+	go cancel()
+
+	var received []string
+	var flushed bool
+	for len(received) < 3 {
+		select {
+		case cmd := <-lis:
+			received = append(received, cmd)
+			if cmd == "flush" {
+				// XXX: nothing more must arrive until the flush is acknowledged.
+				select {
+				case cmd := <-lis:
+					t.Fatalf("Received %s before acknowledging", cmd)
+				case <-time.After(10 * time.Millisecond):
+				}
+				flushed = Ack(lis)
+			}
+		case <-time.After(failureTimeout):
+			t.Fatalf("Timeout, received: %v", received)
+		}
+	}
+
+	if !flushed {
+		t.Fatal("The flush was not waiting for an acknowledgement")
+	}
+
+	if received[0] != "pause" || received[1] != "flush" || received[2] != "stop" {
+		t.Fatalf("Unexpected order: %v", received)
+	}
+}
+
+func ExampleSetSequencePolicy() {
+	tagged, cancel := WithCancel(Tagged[string]())
+	tagged.WithContextPolicy(Default(
+		SetSequencePolicy(map[any][]Step[string]{
+			"storage": {{Cmd: "flush"}, {Cmd: "stop"}},
+		}),
+		"stop",
+	))
+
+	lisStorage := WithTag(tagged, "storage").Cmd()
+
+	go cancel()
+
+	fmt.Println(<-lisStorage)
+	fmt.Println(<-lisStorage)
+	// Output:
+	// flush
+	// stop
+}
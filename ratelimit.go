@@ -0,0 +1,298 @@
+package conductor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxDelay = time.Second
+	defaultForget   = time.Hour
+)
+
+// ErrRateLimited is the error wrapped by a [*RateLimitError].
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitError is the error a [Limiter] rejects a command with, when it exceeds the
+// limit of one of its keys.
+type RateLimitError struct {
+	// Key is the key whose limit was exceeded.
+	Key string
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: %q", ErrRateLimited, e.Key)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// LimitAction tells what a [Limiter] does with a command exceeding a limit.
+type LimitAction int
+
+const (
+	// LimitReject rejects the command with a [*RateLimitError], that [TrySend] and
+	// [Request] return. It is the default.
+	LimitReject LimitAction = iota
+	// LimitDelay holds the command back until the limit allows it, blocking the
+	// sender meanwhile, for at most [RateLimit.MaxDelay]. The commands that would be
+	// held back longer are rejected, as are the ones held back when
+	// [RateLimit.Done] is closed.
+	LimitDelay
+	// LimitDrop drops the command, only counting it: the sender is not told, while
+	// each listener a [Request] waits for replies with [ErrDropped].
+	LimitDrop
+)
+
+// Limit is a token bucket: it allows a command every interval, on average, and up to
+// Burst commands at once. The zero value allows every command.
+type Limit struct {
+	Every time.Duration
+	Burst int
+}
+
+// LimitStats counts what a [Limiter] did with the commands of a key.
+type LimitStats struct {
+	Allowed  uint64
+	Delayed  uint64
+	Rejected uint64
+	Dropped  uint64
+}
+
+// RateLimit configures a [Limiter].
+type RateLimit[T any] struct {
+	// Keys returns the keys a command is limited by. Defaults to [ByTag].
+	Keys func(Envelope[T]) []string
+	// Default is the limit of the keys not listed in Limits.
+	Default Limit
+	// Limits are the limits of specific keys.
+	Limits map[string]Limit
+	// OnLimit is what to do with the commands exceeding a limit.
+	OnLimit LimitAction
+	// MaxDelay is the longest a command is held back with [LimitDelay]. Defaults to
+	// one second.
+	MaxDelay time.Duration
+	// Done, if set, releases the commands held back with [LimitDelay], rejecting
+	// them, once closed, e.g. the one returned by [Conductor.Done].
+	Done <-chan struct{}
+	// Forget is how long the stats of a key are kept after its last command, so
+	// that the keys seen once, e.g. of many senders, do not pile up. Defaults to one
+	// hour.
+	Forget time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// keyStats are the stats of a key, along with the time of its last command.
+type keyStats struct {
+	LimitStats
+	last time.Time
+}
+
+// Limiter limits the rate of the commands going through a [Conductor], with a token
+// bucket per key, e.g. per tag or per sender. Its [Limiter.Intercept] method is an
+// [Interceptor], to be attached with [InterceptSend], to limit the commands sent, or
+// with [InterceptReceive], to limit the ones delivered to each listener. See
+// [NewLimiter].
+type Limiter[T any] struct {
+	cfg     RateLimit[T]
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	stats   map[string]*keyStats
+	swept   time.Time
+}
+
+// NewLimiter creates a [Limiter] with the given configuration.
+func NewLimiter[T any](cfg RateLimit[T]) *Limiter[T] {
+	if cfg.Keys == nil {
+		cfg.Keys = ByTag[T]
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = defaultMaxDelay
+	}
+	if cfg.Forget <= 0 {
+		cfg.Forget = defaultForget
+	}
+	return &Limiter[T]{
+		cfg:     cfg,
+		buckets: make(map[string]*bucket),
+		stats:   make(map[string]*keyStats),
+		swept:   time.Now(),
+	}
+}
+
+// ByTag limits each command by the tags it is sent to, broadcast commands being limited
+// by the empty tag.
+func ByTag[T any](env Envelope[T]) []string {
+	if len(env.Tags) == 0 {
+		return []string{""}
+	}
+	keys := make([]string, 0, len(env.Tags))
+	for _, tag := range env.Tags {
+		keys = append(keys, fmt.Sprint(tag))
+	}
+	return keys
+}
+
+// BySender limits each command by the identity of its sender, see [SendAs]. The
+// commands without one are limited by the empty sender.
+func BySender[T any](env Envelope[T]) []string {
+	return []string{env.Meta[SenderKey]}
+}
+
+func (l *Limiter[T]) limit(key string) Limit {
+	if limit, ok := l.cfg.Limits[key]; ok {
+		return limit
+	}
+	return l.cfg.Default
+}
+
+// take takes a token from the bucket of each of the given keys, if all of them have
+// one, or if reserve is true and none has to wait longer than the maximum delay,
+// returning the keys that have to wait for their token and how long. It returns the
+// key limiting the command otherwise, without taking any token.
+func (l *Limiter[T]) take(keys []string, now time.Time, reserve bool) (time.Duration, []string, string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	var wait time.Duration
+	var waiting []string
+	limited := make([]*bucket, 0, len(keys))
+	for _, key := range keys {
+		limit := l.limit(key)
+		if limit.Every <= 0 {
+			continue
+		}
+
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{
+				tokens: float64(max(limit.Burst, 1)),
+				last:   now,
+			}
+			l.buckets[key] = b
+		}
+		b.tokens = min(b.tokens+float64(now.Sub(b.last))/float64(limit.Every), float64(max(limit.Burst, 1)))
+		b.last = now
+
+		if b.tokens < 1 {
+			delay := time.Duration((1 - b.tokens) * float64(limit.Every))
+			if !reserve || delay > l.cfg.MaxDelay {
+				return 0, nil, key, false
+			}
+			wait = max(wait, delay)
+			waiting = append(waiting, key)
+		}
+		limited = append(limited, b)
+	}
+
+	for _, b := range limited {
+		b.tokens--
+	}
+	return wait, waiting, "", true
+}
+
+// sweep forgets the buckets that are full again, as they are the same as new ones,
+// and the stats of the keys idle for longer than configured. It runs at most once per
+// that interval. It must be called holding the lock.
+func (l *Limiter[T]) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.cfg.Forget {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		limit := l.limit(key)
+		if limit.Every <= 0 || b.tokens+float64(now.Sub(b.last))/float64(limit.Every) >= float64(max(limit.Burst, 1)) {
+			delete(l.buckets, key)
+		}
+	}
+	for key, s := range l.stats {
+		if now.Sub(s.last) >= l.cfg.Forget {
+			delete(l.stats, key)
+		}
+	}
+}
+
+func (l *Limiter[T]) count(keys []string, now time.Time, counter func(*LimitStats) *uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		s, ok := l.stats[key]
+		if !ok {
+			s = &keyStats{}
+			l.stats[key] = s
+		}
+		s.last = now
+		*counter(&s.LimitStats)++
+	}
+}
+
+// Intercept is the [Interceptor] limiting the commands. A rejected or dropped command
+// is counted against the key that limited it only, and a delayed one against the keys
+// that held it back, being allowed by the others.
+func (l *Limiter[T]) Intercept(env Envelope[T], next func(Envelope[T]) error) error {
+	keys := l.cfg.Keys(env)
+	now := time.Now()
+
+	wait, waiting, key, ok := l.take(keys, now, l.cfg.OnLimit == LimitDelay)
+	switch {
+	case !ok && l.cfg.OnLimit == LimitDrop:
+		fmt.Fprintf(logFile, "Dropping %s over the limit of %q\n", fmtCmd(env.Cmd), key)
+		l.count([]string{key}, now, func(s *LimitStats) *uint64 { return &s.Dropped })
+		return ErrDropped
+	case !ok:
+		l.count([]string{key}, now, func(s *LimitStats) *uint64 { return &s.Rejected })
+		return &RateLimitError{Key: key}
+	case wait > 0:
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-l.cfg.Done:
+			l.count(waiting[:1], now, func(s *LimitStats) *uint64 { return &s.Rejected })
+			return &RateLimitError{Key: waiting[0]}
+		}
+		l.count(waiting, now, func(s *LimitStats) *uint64 { return &s.Delayed })
+		l.count(allowed(keys, waiting), now, func(s *LimitStats) *uint64 { return &s.Allowed })
+	default:
+		l.count(keys, now, func(s *LimitStats) *uint64 { return &s.Allowed })
+	}
+
+	return next(env)
+}
+
+// allowed returns the keys that are not among the waiting ones.
+func allowed(keys, waiting []string) []string {
+	var others []string
+	for _, key := range keys {
+		if !slices.Contains(waiting, key) {
+			others = append(others, key)
+		}
+	}
+	return others
+}
+
+// Stats returns what the [Limiter] did with the commands of each key so far, for the
+// keys seen within [RateLimit.Forget].
+func (l *Limiter[T]) Stats() map[string]LimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make(map[string]LimitStats, len(l.stats))
+	for key, s := range l.stats {
+		stats[key] = s.LimitStats
+	}
+	return stats
+}
@@ -0,0 +1,241 @@
+package conductor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterRejectByTag(t *testing.T) {
+	c := Tagged[string]()
+	lis := WithTag(c, "reset").Cmd()
+
+	limiter := NewLimiter(RateLimit[string]{
+		Limits: map[string]Limit{
+			"reset": {Every: time.Hour, Burst: 2},
+		},
+	})
+	defer InterceptSend(c, limiter.Intercept)()
+
+	for i := 0; i < 2; i++ {
+		if err := TrySend(c, "reset")("reset"); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		expectCmd(t, lis, "reset")
+	}
+
+	err := TrySend(c, "reset")("reset")
+	var rerr *RateLimitError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &rerr) || rerr.Key != "reset" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectNoCmd(t, lis)
+
+	// The other tags are not limited.
+	for i := 0; i < 5; i++ {
+		if err := TrySend(c, "other")("ciao"); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	stats := limiter.Stats()
+	if s := stats["reset"]; s.Allowed != 2 || s.Rejected != 1 {
+		t.Fatalf("Unexpected stats: %+v", s)
+	}
+	if s := stats["other"]; s.Allowed != 5 {
+		t.Fatalf("Unexpected stats: %+v", s)
+	}
+}
+
+func TestLimiterDropBySender(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	limiter := NewLimiter(RateLimit[string]{
+		Keys:    BySender[string],
+		Default: Limit{Every: time.Hour},
+		OnLimit: LimitDrop,
+	})
+	defer InterceptSend(c, limiter.Intercept)()
+
+	automation := SendAs(c, "automation")
+	for i := 0; i < 3; i++ {
+		if err := automation("reset"); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	expectCmd(t, lis, "reset")
+	expectNoCmd(t, lis)
+
+	if err := SendAs(c, "operator")("reset"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expectCmd(t, lis, "reset")
+
+	if s := limiter.Stats()["automation"]; s.Allowed != 1 || s.Dropped != 2 {
+		t.Fatalf("Unexpected stats: %+v", s)
+	}
+
+	// The dropped commands are reported to the requests waiting for them.
+	Send(c)("reset")
+	expectCmd(t, lis, "reset")
+
+	ctx, cancel := context.WithTimeout(context.Background(), failureTimeout)
+	defer cancel()
+	replies, err := Request[string](c)(ctx, "reset")
+	if err != nil || len(replies) != 1 || replies[0] != ErrDropped {
+		t.Fatalf("Unexpected replies: %v, %v", replies, err)
+	}
+}
+
+func TestLimiterDropReceive(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	limiter := NewLimiter(RateLimit[string]{
+		Default: Limit{Every: time.Hour},
+		OnLimit: LimitDrop,
+	})
+	defer InterceptReceive(c, limiter.Intercept)()
+
+	Send(c)("reset")
+	expectCmd(t, lis, "reset")
+
+	ctx, cancel := context.WithTimeout(context.Background(), failureTimeout)
+	defer cancel()
+	replies, err := Request[string](c)(ctx, "reset")
+	if err != nil || len(replies) != 1 || replies[0] != ErrDropped {
+		t.Fatalf("Unexpected replies: %v, %v", replies, err)
+	}
+	expectNoCmd(t, lis)
+}
+
+func TestLimiterCountsLimitingKey(t *testing.T) {
+	c := Tagged[string]()
+
+	limiter := NewLimiter(RateLimit[string]{
+		Limits: map[string]Limit{
+			"a": {Every: time.Hour},
+		},
+	})
+	defer InterceptSend(c, limiter.Intercept)()
+
+	for i := 0; i < 2; i++ {
+		TrySend(c, "a", "b")("ciao")
+	}
+
+	stats := limiter.Stats()
+	if s := stats["a"]; s.Allowed != 1 || s.Rejected != 1 {
+		t.Fatalf("Unexpected stats: %+v", s)
+	}
+	if s := stats["b"]; s.Allowed != 1 || s.Rejected != 0 {
+		t.Fatalf("Unexpected stats: %+v", s)
+	}
+}
+
+func TestLimiterDelay(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	every := 20 * time.Millisecond
+	limiter := NewLimiter(RateLimit[string]{
+		Default: Limit{Every: every},
+		OnLimit: LimitDelay,
+	})
+	defer InterceptSend(c, limiter.Intercept)()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		Send(c)("tick")
+		<-lis
+	}
+
+	if elapsed := time.Since(start); elapsed < 2*every-time.Millisecond {
+		t.Fatalf("Commands not delayed: %s", elapsed)
+	}
+	if s := limiter.Stats()[""]; s.Allowed != 1 || s.Delayed != 2 {
+		t.Fatalf("Unexpected stats: %+v", s)
+	}
+}
+
+func TestLimiterDelayBounded(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	done := make(chan struct{})
+	limiter := NewLimiter(RateLimit[string]{
+		Limits: map[string]Limit{
+			"":     {Every: time.Hour},
+			"fast": {Every: time.Hour},
+		},
+		Keys: func(env Envelope[string]) []string {
+			return []string{env.Cmd}
+		},
+		OnLimit:  LimitDelay,
+		MaxDelay: 2 * time.Hour,
+		Done:     done,
+	})
+	defer InterceptSend(c, limiter.Intercept)()
+
+	Send(c)("fast")
+	expectCmd(t, lis, "fast")
+
+	// The next one would wait an hour, until the limiter is done.
+	errs := make(chan error, 1)
+	go func() { errs <- TrySend(c)("fast") }()
+	time.Sleep(10 * time.Millisecond)
+	close(done)
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Delayed command not released")
+	}
+
+	// Past the maximum delay, commands are rejected straight away.
+	other := Simple[string]()
+	lis = other.Cmd()
+
+	bounded := NewLimiter(RateLimit[string]{
+		Default: Limit{Every: time.Hour},
+		OnLimit: LimitDelay,
+	})
+	defer InterceptSend(other, bounded.Intercept)()
+
+	Send(other)("slow")
+	expectCmd(t, lis, "slow")
+	start := time.Now()
+	if err := TrySend(other)("slow"); !errors.Is(err, ErrRateLimited) || time.Since(start) > failureTimeout {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestLimiterForget(t *testing.T) {
+	c := Tagged[string]()
+
+	forget := 20 * time.Millisecond
+	limiter := NewLimiter(RateLimit[string]{
+		Default: Limit{Every: time.Millisecond},
+		Forget:  forget,
+	})
+	defer InterceptSend(c, limiter.Intercept)()
+
+	for i := 0; i < 100; i++ {
+		TrySend(c, i)("ciao")
+	}
+	time.Sleep(2 * forget)
+	TrySend(c, "last")("ciao")
+
+	if stats := limiter.Stats(); len(stats) != 1 {
+		t.Fatalf("Idle keys not forgotten: %d", len(stats))
+	}
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if len(limiter.buckets) != 1 {
+		t.Fatalf("Full buckets not forgotten: %d", len(limiter.buckets))
+	}
+}
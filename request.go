@@ -0,0 +1,158 @@
+package conductor
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrReleased is the reply received from a listener that exited, using [Release],
+// before answering a [Request].
+var ErrReleased = errors.New("listener released")
+
+// ErrEvicted is the reply received from a listener that was evicted, because it was
+// too slow to receive a command (see [EvictSlow]), before answering a [Request].
+var ErrEvicted = errors.New("listener evicted")
+
+// Requester is the return type of the [Request] function.
+type Requester[T any] func(ctx context.Context, cmd T) ([]any, error)
+
+type request struct {
+	replies chan any
+}
+
+// XXX: pending requests are indexed by the listener channel, as it is the only
+// thing that is shared by a listener and all the copies of a conductor.
+var inflight = struct {
+	sync.Mutex
+	byListener map[any][]*request
+}{
+	byListener: make(map[any][]*request),
+}
+
+func (r *request) register(targets []any) {
+	inflight.Lock()
+	defer inflight.Unlock()
+
+	for _, lis := range targets {
+		inflight.byListener[lis] = append(inflight.byListener[lis], r)
+	}
+}
+
+func (r *request) unregister(targets []any) {
+	inflight.Lock()
+	defer inflight.Unlock()
+
+	for _, lis := range targets {
+		pending := inflight.byListener[lis]
+		for i, req := range pending {
+			if req == r {
+				pending = append(pending[:i], pending[i+1:]...)
+				break
+			}
+		}
+		if len(pending) == 0 {
+			delete(inflight.byListener, lis)
+		} else {
+			inflight.byListener[lis] = pending
+		}
+	}
+}
+
+func channels[T any](conductor Conductor[T], args []any) []chan T {
+	if s, ok := subOf(conductor); ok {
+		return s.channels(args)
+	}
+
+	switch c := any(unwrap(conductor)).(type) {
+	case *simple[T]:
+		return c.channels()
+	case *tagged[T]:
+		return c.channels(args)
+	default:
+		panic("conductor not supported")
+	}
+}
+
+// Request may be used on a [Conductor] to create a function that sends a command, in the
+// same way [Send] does, and then waits for all the listeners that received it to answer
+// using [Reply]. The replies are returned in the order they arrive. If the given
+// [context.Context] expires before every listener answered, the replies collected so far
+// are returned along with the error of the context. A listener whose command was
+// dropped by an [Interceptor] replies with [ErrDropped].
+func Request[T any](conductor Conductor[T], args ...any) Requester[T] {
+	send := sender(conductor, args)
+
+	return func(ctx context.Context, cmd T) ([]any, error) {
+		return collect(ctx, channels(conductor, args), func() error {
+			return send(cmd)
+		})
+	}
+}
+
+// collect calls send, and then waits for a reply from each of the given listeners,
+// unless sending fails. A dropped command gets [ErrDropped] as the reply of each.
+func collect[T any](ctx context.Context, chans []chan T, send func() error) ([]any, error) {
+	targets := make([]any, len(chans))
+	for i, ch := range chans {
+		targets[i] = (<-chan T)(ch)
+	}
+
+	req := &request{
+		replies: make(chan any, len(targets)),
+	}
+	req.register(targets)
+	defer req.unregister(targets)
+
+	if err := send(); errors.Is(err, ErrDropped) {
+		replies := make([]any, len(targets))
+		for i := range replies {
+			replies[i] = err
+		}
+		return replies, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	replies := make([]any, 0, len(targets))
+	for len(replies) < len(targets) {
+		select {
+		case reply := <-req.replies:
+			replies = append(replies, reply)
+		case <-ctx.Done():
+			return replies, ctx.Err()
+		}
+	}
+
+	return replies, nil
+}
+
+// Reply answers the oldest pending [Request] delivered to the given listener, that is
+// the channel returned by [Conductor.Cmd]. It returns false if there is no request
+// waiting for an answer from that listener.
+func Reply[T any](lis <-chan T, reply any) bool {
+	inflight.Lock()
+	defer inflight.Unlock()
+
+	pending := inflight.byListener[lis]
+	if len(pending) == 0 {
+		return false
+	}
+
+	req := pending[0]
+	if len(pending) == 1 {
+		delete(inflight.byListener, lis)
+	} else {
+		inflight.byListener[lis] = pending[1:]
+	}
+	req.replies <- reply
+
+	return true
+}
+
+// Ack acknowledges the oldest pending [Request] delivered to the given listener, with
+// an empty reply. It is a shorthand for [Reply] with a nil reply, to be used when the
+// sender only needs to know that the command was handled.
+func Ack[T any](lis <-chan T) bool {
+	return Reply(lis, nil)
+}
@@ -0,0 +1,150 @@
+package conductor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func replier(lis <-chan string, reply string) {
+	go func() {
+		<-lis
+		Reply(lis, reply)
+	}()
+}
+
+func TestRequest_simple(t *testing.T) {
+	c := Simple[string]()
+
+	replier(c.Cmd(), "first")
+	replier(c.Cmd(), "second")
+
+	ctx, cancel := context.WithTimeout(context.Background(), failureTimeout)
+	defer cancel()
+
+	replies, err := Request(c)(ctx, "ciao")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replies) != 2 {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+}
+
+func TestRequest_tagged(t *testing.T) {
+	c := Tagged[string]()
+
+	replier(WithTag(c, "first").Cmd(), "first")
+	replier(WithTag(c, "second").Cmd(), "second")
+
+	ctx, cancel := context.WithTimeout(context.Background(), failureTimeout)
+	defer cancel()
+
+	replies, err := Request(c, "first")(ctx, "ciao")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(replies) != 1 || replies[0] != "first" {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+}
+
+func TestRequest_timeout(t *testing.T) {
+	c := Simple[string]()
+
+	replier(c.Cmd(), "first")
+	silent := c.Cmd()
+
+	ctx, cancel := context.WithTimeout(context.Background(), failureTimeout)
+	defer cancel()
+
+	replies, err := Request(c)(ctx, "ciao")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(replies) != 1 || replies[0] != "first" {
+		t.Fatalf("Unexpected replies: %v", replies)
+	}
+
+	<-silent
+	if Reply(silent, "late") {
+		t.Fatal("Late reply should not find a pending request")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c := Tagged[string]()
+	envelopes, stop := Watch(c)
+
+	go Send(c, "first")("ciao")
+
+	select {
+	case env := <-envelopes:
+		if env.Cmd != "ciao" || len(env.Tags) != 1 || env.Tags[0] != "first" {
+			t.Fatalf("Unexpected envelope: %+v", env)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+
+	stop()
+	if _, ok := <-envelopes; ok {
+		t.Fatal("Channel should be closed after stop")
+	}
+}
+
+func TestFollow(t *testing.T) {
+	c := Simple[int]()
+	envelopes, stop := Follow(c)
+
+	const n = 3 * watchBufSize
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			Send(c)(i)
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		select {
+		case env := <-envelopes:
+			if env.Cmd != i {
+				t.Fatalf("Unexpected envelope: %+v (expected %d)", env, i)
+			}
+		case <-time.After(failureTimeout):
+			t.Fatalf("Envelope %d lost", i)
+		}
+	}
+	<-done
+
+	stop()
+	if _, ok := <-envelopes; ok {
+		t.Fatal("Channel should be closed after stop")
+	}
+
+	// The senders are not held by a stopped follower.
+	Send(c)(n)
+}
+
+func TestTagsAndListeners(t *testing.T) {
+	c := Tagged[string]()
+
+	c.Cmd()
+	WithTag(c, "second").Cmd()
+	WithTag(c, "first").Cmd()
+
+	tags := Tags(c)
+	if len(tags) != 2 || tags[0] != "first" || tags[1] != "second" {
+		t.Fatalf("Unexpected tags: %v", tags)
+	}
+
+	listeners := Listeners(c)
+	if len(listeners) != 3 || len(listeners[""]) != 1 || len(listeners["first"]) != 1 {
+		t.Fatalf("Unexpected listeners: %v", listeners)
+	}
+}
@@ -0,0 +1,129 @@
+// Package router dispatches the commands of a [conductor.Conductor] whose commands are
+// an interface to handlers registered per concrete type, so that adding a new command
+// type does not require touching the type switch of every listener.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrUnhandled is the error returned by [Router.Handle] for a command no handler is
+// registered for, when there is no unhandled-command hook.
+var ErrUnhandled = errors.New("unhandled command")
+
+type handler[T any] func(ctx context.Context, cmd T) error
+
+// route is a handler registered for an interface type, matched by type assertion.
+type route[T any] struct {
+	typ    reflect.Type
+	match  func(T) bool
+	handle handler[T]
+}
+
+// Router calls the handler registered for the concrete type of each command. See [On].
+type Router[T any] struct {
+	mu        sync.RWMutex
+	exact     map[reflect.Type]handler[T]
+	routes    []route[T]
+	unhandled handler[T]
+}
+
+// New creates an empty [Router].
+func New[T any]() *Router[T] {
+	return &Router[T]{
+		exact: make(map[reflect.Type]handler[T]),
+	}
+}
+
+// On registers fn as the handler of the commands of type C, replacing the previous one.
+// If C is itself an interface, fn handles the commands implementing it that have no
+// handler for their concrete type, the interfaces being tried in the order they were
+// registered.
+func On[C, T any](r *Router[T], fn func(C)) {
+	OnContext(r, func(_ context.Context, cmd C) error {
+		fn(cmd)
+		return nil
+	})
+}
+
+// OnContext is like [On], for handlers that take a context and may fail.
+func OnContext[C, T any](r *Router[T], fn func(context.Context, C) error) {
+	typ := reflect.TypeOf((*C)(nil)).Elem()
+	h := func(ctx context.Context, cmd T) error {
+		return fn(ctx, any(cmd).(C))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if typ.Kind() != reflect.Interface {
+		r.exact[typ] = h
+		return
+	}
+
+	for i, rt := range r.routes {
+		if rt.typ == typ {
+			r.routes[i].handle = h
+			return
+		}
+	}
+	r.routes = append(r.routes, route[T]{
+		typ: typ,
+		match: func(cmd T) bool {
+			_, ok := any(cmd).(C)
+			return ok
+		},
+		handle: h,
+	})
+}
+
+// Unhandled registers fn as the hook called with the commands no handler is registered
+// for, replacing the previous one.
+func (r *Router[T]) Unhandled(fn func(context.Context, T) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unhandled = fn
+}
+
+// lookup returns the handler of the command, falling back to the unhandled-command hook,
+// and tells whether it was registered for the command.
+func (r *Router[T]) lookup(cmd T) (handler[T], bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if h, ok := r.exact[reflect.TypeOf(cmd)]; ok {
+		return h, true
+	}
+	for _, rt := range r.routes {
+		if rt.match(cmd) {
+			return rt.handle, true
+		}
+	}
+	return r.unhandled, false
+}
+
+// Handle calls the handler registered for the command, or the unhandled-command hook,
+// and returns its error, or [ErrUnhandled] if there is none. It may be given to
+// [conductor.Handle], to route the commands of a listener.
+func (r *Router[T]) Handle(ctx context.Context, cmd T) error {
+	h, _ := r.lookup(cmd)
+	if h == nil {
+		return fmt.Errorf("%w: %T", ErrUnhandled, cmd)
+	}
+	return h(ctx, cmd)
+}
+
+// Route is like [Router.Handle], for hand-written select loops: it tells whether a
+// handler is registered for the command, the unhandled-command hook not counting, and
+// drops the error.
+func (r *Router[T]) Route(cmd T) bool {
+	h, handled := r.lookup(cmd)
+	if h != nil {
+		h(context.Background(), cmd)
+	}
+	return handled
+}
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+)
+
+type command interface {
+	fmt.Stringer
+}
+
+type pause struct{}
+
+func (pause) String() string { return "pause" }
+
+type resume struct{ after time.Duration }
+
+func (resume) String() string { return "resume" }
+
+type reload struct{}
+
+func (reload) String() string { return "reload" }
+
+type urgent interface {
+	command
+	Urgent()
+}
+
+type abort struct{}
+
+func (abort) String() string { return "abort" }
+func (abort) Urgent()        {}
+
+func TestRouter(t *testing.T) {
+	r := New[command]()
+
+	var got []string
+	On(r, func(pause) { got = append(got, "pause") })
+	On(r, func(cmd resume) { got = append(got, fmt.Sprint("resume ", cmd.after)) })
+	On(r, func(cmd urgent) { got = append(got, "urgent "+cmd.String()) })
+
+	for _, cmd := range []command{pause{}, resume{after: time.Second}, abort{}} {
+		if !r.Route(cmd) {
+			t.Fatalf("Command %s not handled", cmd)
+		}
+	}
+	if r.Route(reload{}) {
+		t.Fatal("Command reload must not be handled")
+	}
+
+	expected := []string{"pause", "resume 1s", "urgent abort"}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("Unexpected handled commands: %v (expected %v)", got, expected)
+	}
+}
+
+func TestRouterUnhandled(t *testing.T) {
+	r := New[command]()
+	On(r, func(pause) {})
+
+	if err := r.Handle(context.Background(), reload{}); !errors.Is(err, ErrUnhandled) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var unhandled []command
+	r.Unhandled(func(_ context.Context, cmd command) error {
+		unhandled = append(unhandled, cmd)
+		return nil
+	})
+
+	if err := r.Handle(context.Background(), reload{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := r.Handle(context.Background(), pause{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(unhandled) != 1 || unhandled[0] != (reload{}) {
+		t.Fatalf("Unexpected unhandled commands: %v", unhandled)
+	}
+}
+
+func TestRouterWithHandle(t *testing.T) {
+	c := conductor.Simple[command]()
+	r := New[command]()
+
+	failure := errors.New("cannot reload")
+	OnContext(r, func(context.Context, reload) error { return failure })
+	On(r, func(pause) {})
+
+	h := conductor.Handle(c, "", r.Handle)
+	defer h.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for cmd, expected := range map[command]error{
+		pause{}:  nil,
+		reload{}: failure,
+		resume{}: ErrUnhandled,
+	} {
+		replies, err := conductor.Request(c)(ctx, cmd)
+		if err != nil || len(replies) != 1 {
+			t.Fatalf("Unexpected replies: %v %v", replies, err)
+		}
+		if expected == nil {
+			if replies[0] != nil {
+				t.Fatalf("Unexpected reply to %s: %v", cmd, replies[0])
+			}
+		} else if err, _ := replies[0].(error); !errors.Is(err, expected) {
+			t.Fatalf("Unexpected reply to %s: %v", cmd, replies[0])
+		}
+	}
+}
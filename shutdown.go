@@ -0,0 +1,124 @@
+package conductor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Stage is a step of a staged shutdown, see [Shutdown].
+type Stage[T any] struct {
+	// Name identifies the stage when reporting a failure.
+	Name string
+	// Tags are the tags whose listeners take part in the stage. Unlike [Send], the
+	// listeners without a tag are not involved. It is ignored for a Simple [Conductor].
+	Tags []any
+	// Cmd is the command sent to the listeners of the stage.
+	Cmd T
+	// Timeout is the longest time waited for the listeners to drain. Zero means to
+	// wait as long as the context given to [Shutdown] allows.
+	Timeout time.Duration
+}
+
+// StageError reports a stage of a [Shutdown] that failed to drain.
+type StageError struct {
+	// Stage is the name of the stage.
+	Stage string
+	// Pending is the number of listeners that neither acknowledged nor exited.
+	Pending int
+	// Err is the reason why the stage was not waited for anymore.
+	Err error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %s failed to drain (%d pending listeners): %s", e.Stage, e.Pending, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Shutdown sends the command of each stage to its listeners, one stage after the
+// other, e.g. stopping the ingress, then the workers and finally the storage. Before
+// moving to the next stage, it waits for every listener of the current one to either
+// acknowledge the command, with [Ack] or [Reply], or to exit, with [Release]. It stops
+// at the first stage that fails to drain in time, returning a [*StageError]. The
+// commands are sent like with [Send], going through the send [Interceptor]s and
+// reaching the children of the [Conductor], that are not waited for.
+func Shutdown[T any](ctx context.Context, conductor Conductor[T], stages ...Stage[T]) error {
+	if s, ok := subOf(conductor); ok {
+		scoped := make([]Stage[T], 0, len(stages))
+		for _, stage := range stages {
+			stage.Tags = s.qualify(stage.Tags)
+			scoped = append(scoped, stage)
+		}
+		return Shutdown[T](ctx, s.wrapped, scoped...)
+	}
+
+	for _, stage := range stages {
+		if err := drain(ctx, conductor, stage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func drain[T any](ctx context.Context, conductor Conductor[T], stage Stage[T]) error {
+	if stage.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, stage.Timeout)
+		defer cancel()
+	}
+
+	var chans []chan T
+	var send func() error
+	switch c := any(unwrap(conductor)).(type) {
+	case *simple[T]:
+		chans = c.channels()
+		send = func() error {
+			return c.send(newEnvelope(stage.Cmd, nil, ""))
+		}
+	case *tagged[T]:
+		c.mu.RLock()
+		for _, tag := range stage.Tags {
+			if s, ok := c.tagged[tag]; ok {
+				chans = append(chans, s.channels()...)
+			}
+		}
+		c.mu.RUnlock()
+
+		send = func() error {
+			return c.hub.intercept(newEnvelope(stage.Cmd, stage.Tags, ""), func(env Envelope[T]) {
+				c.hub.observe(env)
+				c.deliverTo(env, env.Tags)
+				c.hub.propagate(env)
+			})
+		}
+	default:
+		panic("conductor not supported")
+	}
+
+	// XXX: the command is sent from its own goroutine, so that a listener that does
+	// not receive it cannot hold the stage past its context.
+	replies, err := collect(ctx, chans, func() error {
+		sent := make(chan error, 1)
+		go func() { sent <- send() }()
+
+		select {
+		case err := <-sent:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil {
+		return &StageError{
+			Stage:   stage.Name,
+			Pending: len(chans) - len(replies),
+			Err:     err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package conductor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	c := Tagged[string]()
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	ingress := WithTag(c, "ingress").Cmd()
+	workers := WithTag(c, "workers").Cmd()
+	storage := WithTag(c, "storage").Cmd()
+	catchAll := c.Cmd()
+
+	go func() {
+		<-ingress
+		time.Sleep(10 * time.Millisecond)
+		record("ingress")
+		Ack(ingress)
+	}()
+	go func() {
+		<-workers
+		record("workers")
+		// The worker exits instead of acknowledging.
+		Release[string](c, workers)
+	}()
+	go func() {
+		<-storage
+		record("storage")
+		Ack(storage)
+	}()
+
+	err := Shutdown[string](context.Background(), c,
+		Stage[string]{Name: "ingress", Tags: []any{"ingress"}, Cmd: "stop", Timeout: failureTimeout},
+		Stage[string]{Name: "workers", Tags: []any{"workers"}, Cmd: "stop", Timeout: failureTimeout},
+		Stage[string]{Name: "storage", Tags: []any{"storage"}, Cmd: "flush", Timeout: failureTimeout},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(order) != 3 || order[0] != "ingress" || order[1] != "workers" || order[2] != "storage" {
+		t.Fatalf("Unexpected order: %v", order)
+	}
+
+	select {
+	case cmd := <-catchAll:
+		t.Fatalf("Untagged listener received %s", cmd)
+	default:
+	}
+}
+
+func TestShutdown_failedStage(t *testing.T) {
+	c := Tagged[string]()
+
+	WithTag(c, "workers").Cmd()
+	storage := WithTag(c, "storage").Cmd()
+
+	err := Shutdown[string](context.Background(), c,
+		Stage[string]{Name: "workers", Tags: []any{"workers"}, Cmd: "stop", Timeout: 10 * time.Millisecond},
+		Stage[string]{Name: "storage", Tags: []any{"storage"}, Cmd: "flush"},
+	)
+
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stageErr.Stage != "workers" || stageErr.Pending != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Unexpected report: %+v", stageErr)
+	}
+
+	select {
+	case cmd := <-storage:
+		t.Fatalf("Storage received %s after a failed stage", cmd)
+	default:
+	}
+}
+
+func TestShutdown_blockedListener(t *testing.T) {
+	c := Tagged[string]()
+
+	workers := WithTag(c, "workers").Cmd()
+	for i := 0; i < cap(workers); i++ {
+		Send(c, "workers")("work")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Shutdown[string](context.Background(), c,
+			Stage[string]{Name: "workers", Tags: []any{"workers"}, Cmd: "stop", Timeout: 10 * time.Millisecond},
+		)
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown held by a listener not receiving")
+	}
+}
+
+func TestShutdown_sendPath(t *testing.T) {
+	c := Tagged[string]()
+	child, cancel := Child[string](c)
+	defer cancel()
+
+	rejected := errors.New("rejected")
+	stop := InterceptSend(c, func(env Envelope[string], next func(Envelope[string]) error) error {
+		if env.Cmd == "refused" {
+			return rejected
+		}
+		return next(env)
+	})
+	defer stop()
+
+	childWorkers := WithTag(child, "workers").Cmd()
+
+	err := Shutdown[string](context.Background(), c,
+		Stage[string]{Name: "refused", Tags: []any{"workers"}, Cmd: "refused", Timeout: failureTimeout},
+	)
+	if !errors.Is(err, rejected) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := Shutdown[string](context.Background(), c,
+		Stage[string]{Name: "workers", Tags: []any{"workers"}, Cmd: "stop", Timeout: failureTimeout},
+	); err != nil {
+		t.Fatal(err)
+	}
+	expectCmd(t, childWorkers, "stop")
+}
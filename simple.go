@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/signal"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,9 +25,12 @@ var (
 
 type simple[T any] struct {
 	listeners map[string]chan T
-	mu        sync.RWMutex
+	evicted   map[string]struct{}
+	mu        *sync.RWMutex
 	ctx       context.Context
 	logFile   *os.File
+	hub       *hub[T]
+	tag       any
 }
 
 /* Implement context.Context */
@@ -64,10 +67,12 @@ func (c *simple[T]) WithContext(ctx context.Context) Conductor[T] {
 }
 
 func (c *simple[T]) WithContextPolicy(policy Policy[T]) Conductor[T] {
+	fired := c.hub.attachPolicy(c.ctx)
 	go func() {
+		defer fired()
 		<-c.ctx.Done()
-		if cmd, ok := policy.Decide(); ok {
-			c.send(cmd)
+		if steps, ok := decideSteps(policy, c.ctx.Err(), context.Cause(c.ctx)); ok {
+			c.fire(c.hub, nil, steps)
 		}
 	}()
 
@@ -139,6 +144,14 @@ frameLoop:
 		c.mu.RUnlock()
 		return ch
 	}
+	if _, ok := c.evicted[key]; ok {
+		c.mu.RUnlock()
+		// XXX: an evicted listener is not registered again behind its back: it
+		// gets a closed channel, telling it that it was evicted.
+		ch := make(chan T)
+		close(ch)
+		return ch
+	}
 	c.mu.RUnlock()
 
 	c.mu.Lock()
@@ -149,26 +162,169 @@ frameLoop:
 	return lis
 }
 
-func (c *simple[T]) send(cmd T) {
+func (c *simple[T]) send(env Envelope[T]) error {
+	env.Tags = nil
+	return c.hub.intercept(env, c.dispatch)
+}
+
+func (c *simple[T]) dispatch(env Envelope[T]) {
+	c.hub.observe(env)
+	c.deliver(env)
+	c.hub.propagate(env)
+}
+
+func (c *simple[T]) deliver(env Envelope[T]) {
+	after := time.Duration(c.hub.evictAfter.Load())
+
+	// XXX: the listeners are copied, so that the interceptors run, and the
+	// listeners are waited for, without holding the lock.
 	c.mu.RLock()
+	keys := make([]string, 0, len(c.listeners))
+	chans := make([]chan T, 0, len(c.listeners))
 	for k, ch := range c.listeners {
-		fmt.Fprintf(c.logFile, "Sending %s to %s listener\n", fmtCmd(cmd), k)
-		ch <- cmd
+		keys = append(keys, k)
+		chans = append(chans, ch)
 	}
 	c.mu.RUnlock()
+
+	var slow []chan T
+	for i, ch := range chans {
+		fmt.Fprintf(c.logFile, "Sending %s to %s listener\n", fmtCmd(env.Cmd), keys[i])
+
+		err := runChain(c.hub.receiveChain(c.tag, ch), env, func(env Envelope[T]) error {
+			if !push(ch, env.Cmd, after) {
+				slow = append(slow, ch)
+				return nil
+			}
+			c.hub.pushed(ch, env.Origin == OriginPolicy)
+			return nil
+		})
+		if err != nil {
+			rejected(env.Cmd, err)
+			Reply((<-chan T)(ch), err)
+		}
+	}
+
+	for _, ch := range slow {
+		c.evict(ch, after)
+	}
 }
 
-func (c *simple[T]) notify(cmd T, signals ...os.Signal) {
-	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, signals...)
-	for {
-		select {
-		case <-c.Done():
-			return
-		case <-ch:
-			go c.send(cmd)
+// evict releases a listener that did not receive a command within the given interval,
+// answering its pending requests with [ErrEvicted].
+func (c *simple[T]) evict(ch chan T, after time.Duration) {
+	fmt.Fprintf(c.logFile, "Evicting slow listener after %s\n", after)
+	if c.release(ch, listenerEvicted) {
+		for Reply((<-chan T)(ch), ErrEvicted) {
+		}
+	}
+}
+
+// push sends the command to the listener, waiting for it at most the given interval, if
+// positive. It returns false if the listener was too slow.
+func push[T any](ch chan T, cmd T, after time.Duration) bool {
+	if after <= 0 {
+		ch <- cmd
+		return true
+	}
+
+	select {
+	case ch <- cmd:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(after)
+	defer timer.Stop()
+
+	select {
+	case ch <- cmd:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// fire delivers the steps decided by a [Policy] in order, notifying the given hub.
+func (c *simple[T]) fire(h *hub[T], tags []any, steps []Step[T]) {
+	for _, step := range steps {
+		if step.Delay > 0 {
+			time.Sleep(step.Delay)
+		}
+
+		env := newEnvelope(step.Cmd, tags, OriginPolicy)
+		send := func() error {
+			h.observe(env)
+			c.deliver(env)
+			return nil
+		}
+
+		if step.AckTimeout <= 0 {
+			send()
+			continue
 		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), step.AckTimeout)
+		if _, err := collect(ctx, c.channels(), send); err != nil {
+			fmt.Fprintf(c.logFile, "Not all listeners acknowledged %s: %s\n", fmtCmd(step.Cmd), err)
+		}
+		cancel()
+	}
+}
+
+func (c *simple[T]) channels() []chan T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	chans := make([]chan T, 0, len(c.listeners))
+	for _, ch := range c.listeners {
+		chans = append(chans, ch)
+	}
+	return chans
+}
+
+func (c *simple[T]) release(lis <-chan T, kind lifecycleKind) bool {
+	var found bool
+	c.mu.Lock()
+	for k, ch := range c.listeners {
+		if (<-chan T)(ch) == lis {
+			delete(c.listeners, k)
+			found = true
+			if kind == listenerEvicted {
+				if c.evicted == nil {
+					c.evicted = make(map[string]struct{})
+				}
+				c.evicted[k] = struct{}{}
+			}
+			break
+		}
+	}
+	remaining := len(c.listeners)
+	c.mu.Unlock()
+
+	if found {
+		c.hub.unmark(lis)
+		c.hub.left(lis)
+		c.hub.emit(lifecycleEvent{
+			kind:      kind,
+			tag:       c.tag,
+			remaining: remaining,
+		})
+	}
+
+	return found
+}
+
+func (c *simple[T]) keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]string, 0, len(c.listeners))
+	for k := range c.listeners {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
 }
 
 /* Public functions */
@@ -179,6 +335,8 @@ func Simple[T any]() Conductor[T] {
 		ctx:       context.TODO(),
 		logFile:   initLogFile(),
 		listeners: make(map[string]chan T),
+		mu:        new(sync.RWMutex),
+		hub:       newHub[T](),
 	}
 }
 
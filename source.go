@@ -0,0 +1,230 @@
+package conductor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNotFed is the error returned by a [Webhook] receiving a request while no [Feed] is
+// running it.
+var ErrNotFed = errors.New("source not fed to any conductor")
+
+// Source produces commands out of events happening outside the [Conductor], in the same
+// way [Notify] does with signals, see [Feed].
+type Source[T any] interface {
+	// Run calls emit with each command produced, until stop gets closed or the
+	// events are over, and then returns.
+	Run(stop <-chan struct{}, emit func(T)) error
+}
+
+// SourceFunc adapts a function to the [Source] interface.
+type SourceFunc[T any] func(stop <-chan struct{}, emit func(T)) error
+
+func (f SourceFunc[T]) Run(stop <-chan struct{}, emit func(T)) error {
+	return f(stop, emit)
+}
+
+// Feed sends through the [Conductor] the commands produced by the given [Source], until
+// the returned function is called, the [Conductor] is done or the [Source] is over. The
+// optional variadic args are handled as by [Notify], i.e. they may contain the tags the
+// commands are sent to and a [DeliveryMode].
+func Feed[T any](conductor Conductor[T], source Source[T], args ...any) func() {
+	tags, mode := notifyOptions(args)
+	send := Send(conductor, tags...)
+
+	stop := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() { close(stop) })
+	}
+
+	go func() {
+		select {
+		case <-conductor.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+
+	d := newDispatcher(mode, stop, conductor.Done())
+	go func() {
+		defer cancel()
+		defer d.close()
+
+		err := source.Run(stop, func(cmd T) {
+			d.run(func() {
+				send(cmd)
+			})
+		})
+		if err != nil {
+			fmt.Fprintf(logFile, "Source stopped: %s\n", err)
+		}
+	}()
+
+	return cancel
+}
+
+// FileSource is a [Source] that checks the file at the given path at each interval, and
+// calls fn when its modification time or size changed, since the source was created,
+// or when it was created or removed, in which case fn receives nil. The command
+// returned by fn is emitted if the boolean is true.
+func FileSource[T any](path string, interval time.Duration, fn func(os.FileInfo) (T, bool)) Source[T] {
+	// XXX: the file is looked at right away, so that the changes happening before
+	// the source runs are not missed.
+	initial, _ := os.Stat(path)
+
+	return SourceFunc[T](func(stop <-chan struct{}, emit func(T)) error {
+		last := initial
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return nil
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				if !fileChanged(last, info) {
+					continue
+				}
+				last = info
+				if cmd, ok := fn(info); ok {
+					emit(cmd)
+				}
+			}
+		}
+	})
+}
+
+func fileChanged(before, after os.FileInfo) bool {
+	if before == nil || after == nil {
+		return (before == nil) != (after == nil)
+	}
+	return !before.ModTime().Equal(after.ModTime()) || before.Size() != after.Size()
+}
+
+// LineSource is a [Source] that reads the lines of the given reader, e.g. [os.Stdin],
+// and emits the commands obtained parsing them. The lines that cannot be parsed are
+// skipped. Stopping it only takes effect once the next line is read.
+func LineSource[T any](r io.Reader, parse func(string) (T, error)) Source[T] {
+	return SourceFunc[T](func(stop <-chan struct{}, emit func(T)) error {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+
+			cmd, err := parse(scanner.Text())
+			if err != nil {
+				fmt.Fprintf(logFile, "Skipping line %q: %s\n", scanner.Text(), err)
+				continue
+			}
+			emit(cmd)
+		}
+		return scanner.Err()
+	})
+}
+
+// TickerSource is a [Source] that calls fn at each interval, and emits the command it
+// returns if the boolean is true.
+func TickerSource[T any](interval time.Duration, fn func(time.Time) (T, bool)) Source[T] {
+	return SourceFunc[T](func(stop <-chan struct{}, emit func(T)) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return nil
+			case now := <-ticker.C:
+				if cmd, ok := fn(now); ok {
+					emit(cmd)
+				}
+			}
+		}
+	})
+}
+
+// ChanSource is a [Source] that maps each event received from the given channel to a
+// command, using fn, and emits it if the boolean is true. It is over once the channel
+// is closed.
+func ChanSource[T, E any](ch <-chan E, fn func(E) (T, bool)) Source[T] {
+	return SourceFunc[T](func(stop <-chan struct{}, emit func(T)) error {
+		for {
+			select {
+			case <-stop:
+				return nil
+			case ev, ok := <-ch:
+				if !ok {
+					return nil
+				}
+				if cmd, ok := fn(ev); ok {
+					emit(cmd)
+				}
+			}
+		}
+	})
+}
+
+// Webhook is a [Source] that is also an [http.Handler], emitting a command for each
+// request it serves. See [WebhookSource].
+type Webhook[T any] struct {
+	parse func(*http.Request) (T, error)
+	mu    sync.RWMutex
+	emit  func(T)
+}
+
+// WebhookSource creates a [Webhook] that parses each request it serves into a command
+// with the given function. A request is answered with 202 Accepted once its command is
+// emitted, with 400 Bad Request if it cannot be parsed and with 503 Service Unavailable
+// if the [Webhook] is not fed to a [Conductor].
+func WebhookSource[T any](parse func(*http.Request) (T, error)) *Webhook[T] {
+	return &Webhook[T]{
+		parse: parse,
+	}
+}
+
+func (w *Webhook[T]) Run(stop <-chan struct{}, emit func(T)) error {
+	w.mu.Lock()
+	w.emit = emit
+	w.mu.Unlock()
+
+	<-stop
+
+	w.mu.Lock()
+	w.emit = nil
+	w.mu.Unlock()
+
+	return nil
+}
+
+func (w *Webhook[T]) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.emit == nil {
+		http.Error(rw, ErrNotFed.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	cmd, err := w.parse(r)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.emit(cmd)
+	rw.WriteHeader(http.StatusAccepted)
+}
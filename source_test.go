@@ -0,0 +1,141 @@
+package conductor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedChanSource(t *testing.T) {
+	c := Tagged[string]()
+	red := WithTag(c, "red").Cmd()
+	blue := WithTag(c, "blue").Cmd()
+
+	events := make(chan int)
+	stop := Feed[string](c, ChanSource(events, func(n int) (string, bool) {
+		return strings.Repeat("x", n), n > 0
+	}), "red", DeliverOrdered)
+	defer stop()
+
+	events <- 0
+	events <- 2
+	expectCmd(t, red, "xx")
+	expectNoCmd(t, blue)
+
+	close(events)
+}
+
+func TestFeedTickerSource(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	stop := Feed[string](c, TickerSource(5*time.Millisecond, func(time.Time) (string, bool) {
+		return "tick", true
+	}))
+
+	expectCmd(t, lis, "tick")
+	expectCmd(t, lis, "tick")
+
+	stop()
+	time.Sleep(10 * time.Millisecond)
+	for len(lis) > 0 {
+		<-lis
+	}
+	expectNoCmd(t, lis)
+}
+
+func TestFeedFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	stop := Feed[string](c, FileSource(path, 5*time.Millisecond, func(info os.FileInfo) (string, bool) {
+		if info == nil {
+			return "removed", true
+		}
+		return "changed", true
+	}))
+	defer stop()
+
+	if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectCmd(t, lis, "changed")
+	expectNoCmd(t, lis)
+
+	if err := os.WriteFile(path, []byte("ab"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectCmd(t, lis, "changed")
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	expectCmd(t, lis, "removed")
+}
+
+func TestFeedLineSource(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	input := strings.NewReader("pause\nbogus\nresume\n")
+	stop := Feed[string](c, LineSource(input, func(line string) (string, error) {
+		if line == "bogus" {
+			return "", errors.New("unknown command")
+		}
+		return line, nil
+	}))
+	defer stop()
+
+	expectCmd(t, lis, "pause")
+	expectCmd(t, lis, "resume")
+	expectNoCmd(t, lis)
+}
+
+func TestFeedWebhookSource(t *testing.T) {
+	c := Tagged[string]()
+	lis := WithTag(c, "deploy").Cmd()
+
+	hook := WebhookSource(func(r *http.Request) (string, error) {
+		cmd := r.URL.Query().Get("cmd")
+		if cmd == "" {
+			return "", errors.New("missing cmd")
+		}
+		return cmd, nil
+	})
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+
+	post := func(query string) int {
+		t.Helper()
+		resp, err := http.Post(srv.URL+query, "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("?cmd=reload"); code != http.StatusServiceUnavailable {
+		t.Fatalf("Unexpected status before feeding: %d", code)
+	}
+
+	stop := Feed[string](c, hook, "deploy")
+	defer stop()
+	time.Sleep(10 * time.Millisecond)
+
+	if code := post("?cmd=reload"); code != http.StatusAccepted {
+		t.Fatalf("Unexpected status: %d", code)
+	}
+	expectCmd(t, lis, "reload")
+
+	if code := post(""); code != http.StatusBadRequest {
+		t.Fatalf("Unexpected status for a bad request: %d", code)
+	}
+}
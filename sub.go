@@ -0,0 +1,224 @@
+package conductor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SubSeparator separates the name of a sub-conductor from the tags within it, in the
+// tags of the Tagged [Conductor] it is part of.
+const SubSeparator = "/"
+
+type sub[T any] struct {
+	wrapped *tagged[T]
+	prefix  string
+}
+
+/* Implement context.Context */
+
+var _ context.Context = &sub[struct{}]{}
+
+func (s *sub[T]) Deadline() (time.Time, bool) {
+	return s.wrapped.Deadline()
+}
+
+func (s *sub[T]) Done() <-chan struct{} {
+	return s.wrapped.Done()
+}
+
+func (s *sub[T]) Err() error {
+	return s.wrapped.Err()
+}
+
+func (s *sub[T]) Value(key any) any {
+	return s.wrapped.Value(key)
+}
+
+/* Implement Conductor[T] */
+
+// XXX: the listeners without a tag of a sub-conductor are the ones bound to the tag
+// of its namespace in the wrapped conductor.
+func (s *sub[T]) Cmd() <-chan T {
+	return s.wrapped.cmd(s.prefix)
+}
+
+func (s *sub[T]) WithContext(ctx context.Context) Conductor[T] {
+	s.wrapped.WithContext(ctx)
+	return s
+}
+
+func (s *sub[T]) WithContextPolicy(policy Policy[T]) Conductor[T] {
+	s.wrapped.attachPolicy(policy, s.scope)
+	return s
+}
+
+/* Internal functions */
+
+// scope tells if the given tag of the wrapped conductor belongs to the namespace, and
+// returns its name within it.
+func (s *sub[T]) scope(tag any) (any, bool) {
+	name, ok := tag.(string)
+	if !ok {
+		return nil, false
+	}
+	if name == s.prefix {
+		return defaultTag, true
+	}
+	if rest, ok := strings.CutPrefix(name, s.prefix+SubSeparator); ok {
+		return rest, true
+	}
+	return nil, false
+}
+
+// sees tells if the given [Envelope], observed on the wrapped conductor, was sent to
+// the namespace or broadcast.
+func (s *sub[T]) sees(env Envelope[T]) bool {
+	if len(env.Tags) == 0 {
+		return true
+	}
+	for _, tag := range env.Tags {
+		if _, ok := s.scope(tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// qualify returns the tags of the wrapped conductor corresponding to the given ones.
+func (s *sub[T]) qualify(tags []any) []any {
+	qualified := make([]any, 0, len(tags))
+	for _, tag := range tags {
+		qualified = append(qualified, s.prefix+SubSeparator+fmt.Sprint(tag))
+	}
+	return qualified
+}
+
+// members returns the tags of the wrapped conductor belonging to the namespace, always
+// including the tag of the namespace itself.
+func (s *sub[T]) members() []any {
+	s.wrapped.mu.RLock()
+	defer s.wrapped.mu.RUnlock()
+
+	members := []any{s.prefix}
+	for tag := range s.wrapped.tagged {
+		if name, ok := s.scope(tag); ok && name != defaultTag {
+			members = append(members, tag)
+		}
+	}
+	return members
+}
+
+func (s *sub[T]) send(env Envelope[T]) error {
+	// XXX: the envelope of a broadcast lists the tags of the namespace, as no tags
+	// would mean the whole wrapped conductor to the ones observing it.
+	broadcast := len(env.Tags) == 0
+	if broadcast {
+		env.Tags = s.members()
+	} else {
+		env.Tags = s.qualify(env.Tags)
+	}
+
+	return s.wrapped.hub.intercept(env, func(env Envelope[T]) {
+		s.dispatch(env, broadcast)
+	})
+}
+
+// dispatch delivers the envelope within the namespace, then hands it over to the same
+// namespace of the children of the wrapped conductor.
+func (s *sub[T]) dispatch(env Envelope[T], broadcast bool) {
+	s.wrapped.hub.observe(env)
+	if broadcast {
+		s.wrapped.deliverTo(env, env.Tags)
+	} else {
+		s.wrapped.deliverTo(env, append(append([]any(nil), env.Tags...), s.prefix))
+	}
+
+	for _, n := range s.wrapped.hub.nodes() {
+		child := &sub[T]{
+			wrapped: n.(*tagged[T]),
+			prefix:  s.prefix,
+		}
+		if broadcast {
+			env.Tags = child.members()
+		}
+		child.dispatch(env, broadcast)
+	}
+}
+
+func (s *sub[T]) channels(tags []any) []chan T {
+	if len(tags) == 0 {
+		tags = s.members()
+	} else {
+		tags = append(s.qualify(tags), s.prefix)
+	}
+
+	s.wrapped.mu.RLock()
+	defer s.wrapped.mu.RUnlock()
+
+	var chans []chan T
+	for _, tag := range tags {
+		if c, ok := s.wrapped.tagged[tag]; ok {
+			chans = append(chans, c.channels()...)
+		}
+	}
+	return chans
+}
+
+func (s *sub[T]) tags() []string {
+	var tags []string
+	for _, tag := range s.members() {
+		if name, _ := s.scope(tag); name != defaultTag {
+			tags = append(tags, fmt.Sprint(name))
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (s *sub[T]) listeners() map[string][]string {
+	s.wrapped.mu.RLock()
+	defer s.wrapped.mu.RUnlock()
+
+	listeners := make(map[string][]string)
+	for tag, c := range s.wrapped.tagged {
+		name, ok := s.scope(tag)
+		if !ok {
+			continue
+		}
+		if name == defaultTag {
+			name = ""
+		}
+		listeners[fmt.Sprint(name)] = c.keys()
+	}
+	return listeners
+}
+
+/* Public functions */
+
+// Sub returns a [Conductor] that behaves like a Tagged one, but restricted to the tags
+// under the given name of the given Tagged [Conductor]: the tag "primary" of the
+// sub-conductor "db" is the tag "db/primary" of the given one. The commands sent and
+// broadcast through it only reach the listeners of its namespace, and its listeners
+// without a tag are the ones bound to the tag named after it. Using [WithTag] or Sub on
+// a sub-conductor nests the names.
+func Sub[T any](conductor Conductor[T], name string) Conductor[T] {
+	switch c := any(conductor).(type) {
+	case *tagged[T]:
+		return &sub[T]{
+			wrapped: c,
+			prefix:  name,
+		}
+	case *sub[T]:
+		return &sub[T]{
+			wrapped: c.wrapped,
+			prefix:  c.prefix + SubSeparator + name,
+		}
+	case *graceful[T]:
+		return c.derive(Sub(c.wrapped, name))
+	default:
+		panic("not a conductor.Tagged")
+	}
+}
@@ -0,0 +1,110 @@
+package conductor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSub(t *testing.T) {
+	c := Tagged[string]()
+	db := Sub(c, "db")
+
+	root := c.Cmd()
+	web := WithTag(c, "web").Cmd()
+	dbAll := db.Cmd()
+	primary := WithTag(db, "primary").Cmd()
+	replica := WithTag(Sub(db, "replica"), "1").Cmd()
+
+	Send(db, "primary")("flush")
+	expectCmd(t, primary, "flush")
+	expectCmd(t, dbAll, "flush")
+	expectNoCmd(t, root)
+	expectNoCmd(t, replica)
+
+	Send(db)("pause")
+	expectCmd(t, primary, "pause")
+	expectCmd(t, dbAll, "pause")
+	expectCmd(t, replica, "pause")
+	expectNoCmd(t, root)
+	expectNoCmd(t, web)
+
+	// The wrapped conductor addresses the nested names.
+	Send(c, "db/replica/1")("resync")
+	expectCmd(t, replica, "resync")
+	expectCmd(t, root, "resync")
+
+	if tags := Tags(db); len(tags) != 2 || tags[0] != "primary" || tags[1] != "replica/1" {
+		t.Fatalf("Unexpected tags: %v", tags)
+	}
+	if listeners := Listeners(db); len(listeners) != 3 || len(listeners[""]) != 1 {
+		t.Fatalf("Unexpected listeners: %v", listeners)
+	}
+}
+
+func TestSubPolicy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := TaggedFromContext[string](ctx)
+	db := Sub(c, "db").WithContextPolicy(SetPolicy(map[any]string{
+		"primary": "stop",
+		"web":     "unexpected",
+	}))
+
+	primary := WithTag(db, "primary").Cmd()
+	web := WithTag(c, "web").Cmd()
+
+	cancel()
+
+	expectCmd(t, primary, "stop")
+	expectNoCmd(t, web)
+}
+
+func TestSubRequest(t *testing.T) {
+	c := Tagged[string]()
+	db := Sub(c, "db")
+
+	primary := WithTag(db, "primary").Cmd()
+	_ = WithTag(c, "web").Cmd()
+
+	go func() {
+		<-primary
+		Ack(primary)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), failureTimeout)
+	defer cancel()
+	replies, err := Request(db)(ctx, "ping")
+	if err != nil || len(replies) != 1 {
+		t.Fatalf("Unexpected: %v %v", replies, err)
+	}
+}
+
+func TestSubWatch(t *testing.T) {
+	c := Tagged[string]()
+	db := Sub(c, "db")
+
+	_ = WithTag(db, "primary").Cmd()
+	_ = WithTag(c, "web").Cmd()
+
+	watched, stopWatch := Watch[string](db)
+	defer stopWatch()
+	followed, stopFollow := Follow[string](db)
+	defer stopFollow()
+
+	Send(c, "web")("outside")
+	Send(db, "primary")("inside")
+	Send(c)("everyone")
+
+	for name, envelopes := range map[string]<-chan Envelope[string]{"watched": watched, "followed": followed} {
+		for _, expected := range []string{"inside", "everyone"} {
+			select {
+			case env := <-envelopes:
+				if env.Cmd != expected {
+					t.Fatalf("Unexpected %s envelope: %+v (expected %s)", name, env, expected)
+				}
+			case <-time.After(failureTimeout):
+				t.Fatalf("%s: %s not received", name, expected)
+			}
+		}
+	}
+}
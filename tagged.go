@@ -3,8 +3,6 @@ package conductor
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/signal"
 	"sync"
 	"time"
 )
@@ -13,8 +11,9 @@ var defaultTag string = "CONDUCTOR_INTERNAL_DEFAULT_TAG"
 
 type tagged[T any] struct {
 	tagged map[any]*simple[T]
-	mu     sync.RWMutex
+	mu     *sync.RWMutex
 	ctx    context.Context
+	hub    *hub[T]
 }
 
 /* Implement context.Context */
@@ -54,22 +53,53 @@ func (t *tagged[T]) WithContext(ctx context.Context) Conductor[T] {
 }
 
 func (c *tagged[T]) WithContextPolicy(policy Policy[T]) Conductor[T] {
+	c.attachPolicy(policy, func(tag any) (any, bool) {
+		return tag, true
+	})
+
+	return c
+}
+
+/* Internal functions */
+
+// attachPolicy fires the [Policy] to the tags accepted by the given scope function, that
+// also returns the name of the tag as seen by the [Policy].
+func (c *tagged[T]) attachPolicy(policy Policy[T], scope func(tag any) (any, bool)) {
+	fired := c.hub.attachPolicy(c.ctx)
 	go func() {
+		defer fired()
 		<-c.ctx.Done()
-		c.mu.Lock()
-		defer c.mu.Unlock()
+		err, cause := c.ctx.Err(), context.Cause(c.ctx)
+
+		c.mu.RLock()
+		listeners := make(map[any]*simple[T], len(c.tagged))
 		for tag, lis := range c.tagged {
-			if cmd, ok := policy.Decide(tag); ok {
-				lis.send(cmd)
+			listeners[tag] = lis
+		}
+		c.mu.RUnlock()
+
+		// XXX: each tag receives its steps in order, but tags are independent, so
+		// that a delay on one of them does not hold back the others.
+		var wg sync.WaitGroup
+		for tag, lis := range listeners {
+			name, ok := scope(tag)
+			if !ok {
+				continue
 			}
+			steps, ok := decideSteps(policy, err, cause, name)
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func(tag any, lis *simple[T]) {
+				defer wg.Done()
+				lis.fire(c.hub, []any{tag}, steps)
+			}(tag, lis)
 		}
+		wg.Wait()
 	}()
-
-	return c
 }
 
-/* Internal functions */
-
 func (t *tagged[T]) cmd(tag string, discriminator ...any) <-chan T {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -78,55 +108,103 @@ func (t *tagged[T]) cmd(tag string, discriminator ...any) <-chan T {
 	if c, ok := t.tagged[tag]; ok {
 		return c.cmd(3, discriminator...)
 	}
-	c := SimpleFromContext[T](t.ctx)
-	t.tagged[tag] = c.(*simple[T])
-	return c.(*simple[T]).cmd(2, discriminator...)
+	c := SimpleFromContext[T](t.ctx).(*simple[T])
+	c.hub = t.hub
+	c.tag = tag
+	t.tagged[tag] = c
+	return c.cmd(2, discriminator...)
 }
 
-func (t *tagged[T]) send(cmd T, tags []any) {
-	t.mu.RLock()
-	fmt.Fprintf(logFile, "Sending %s to %s listener\n", fmtCmd(cmd), tags)
-	for _, tag := range append(tags, defaultTag) {
-		if c, ok := t.tagged[tag]; ok {
-			c.send(cmd)
+func (t *tagged[T]) send(env Envelope[T]) error {
+	return t.hub.intercept(env, t.dispatch)
+}
+
+func (t *tagged[T]) dispatch(env Envelope[T]) {
+	t.hub.observe(env)
+	t.deliver(env)
+	t.hub.propagate(env)
+}
+
+func (t *tagged[T]) deliver(env Envelope[T]) {
+	if len(env.Tags) == 0 {
+		fmt.Fprintf(logFile, "Sending %s to all listener\n", fmtCmd(env.Cmd))
+		for _, c := range t.all() {
+			c.deliver(env)
 		}
+		return
+	}
+
+	fmt.Fprintf(logFile, "Sending %s to %s listener\n", fmtCmd(env.Cmd), env.Tags)
+	for _, c := range t.targets(append(append([]any(nil), env.Tags...), defaultTag)) {
+		c.deliver(env)
+	}
+}
+
+// deliverTo delivers the command to the listeners of exactly the given tags.
+func (t *tagged[T]) deliverTo(env Envelope[T], tags []any) {
+	fmt.Fprintf(logFile, "Sending %s to %s listener\n", fmtCmd(env.Cmd), tags)
+	for _, c := range t.targets(tags) {
+		c.deliver(env)
 	}
-	t.mu.RUnlock()
 }
 
-func (t *tagged[T]) broadcast(cmd T) {
+// all returns the listeners of all the tags, so that they are delivered to without
+// holding the lock.
+func (t *tagged[T]) all() []*simple[T] {
 	t.mu.RLock()
-	fmt.Fprintf(logFile, "Sending %s to all listener\n", fmtCmd(cmd))
+	defer t.mu.RUnlock()
+
+	targets := make([]*simple[T], 0, len(t.tagged))
 	for _, c := range t.tagged {
-		c.send(cmd)
+		targets = append(targets, c)
+	}
+	return targets
+}
+
+// targets returns the listeners of the given tags, as [tagged.all] does.
+func (t *tagged[T]) targets(tags []any) []*simple[T] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var targets []*simple[T]
+	for _, tag := range tags {
+		if c, ok := t.tagged[tag]; ok {
+			targets = append(targets, c)
+		}
 	}
-	t.mu.RUnlock()
+	return targets
 }
 
-func (t *tagged[T]) notifyAll(cmd T, signals ...os.Signal) {
-	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, signals...)
-	for {
-		select {
-		case <-t.Done():
-			return
-		case <-ch:
-			t.broadcast(cmd)
+func (t *tagged[T]) channels(tags []any) []chan T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var chans []chan T
+	if len(tags) == 0 {
+		for _, c := range t.tagged {
+			chans = append(chans, c.channels()...)
 		}
+		return chans
 	}
+
+	for _, tag := range append(tags, defaultTag) {
+		if c, ok := t.tagged[tag]; ok {
+			chans = append(chans, c.channels()...)
+		}
+	}
+	return chans
 }
 
-func (t *tagged[T]) notifyTagged(cmd T, tags []any, signals []os.Signal) {
-	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, signals...)
-	for {
-		select {
-		case <-t.Done():
-			return
-		case <-ch:
-			t.send(cmd, tags)
+func (t *tagged[T]) release(lis <-chan T) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, c := range t.tagged {
+		if c.release(lis, listenerReleased) {
+			return true
 		}
 	}
+	return false
 }
 
 /* Public functions */
@@ -135,12 +213,22 @@ func (t *tagged[T]) notifyTagged(cmd T, tags []any, signals []os.Signal) {
 func Tagged[T any]() Conductor[T] {
 	return &tagged[T]{
 		tagged: make(map[any]*simple[T]),
+		mu:     new(sync.RWMutex),
 		ctx:    context.TODO(),
+		hub:    newHub[T](),
 	}
 }
 
 // WithTag loads a tagged listener in a Tagged [Conductor].
 func WithTag[T any](conductor Conductor[T], tag string, discriminator ...any) Conductor[T] {
+	if g, ok := any(conductor).(*graceful[T]); ok {
+		return g.derive(WithTag(g.wrapped, tag, discriminator...))
+	}
+
+	if s, ok := any(conductor).(*sub[T]); ok {
+		return WithTag[T](s.wrapped, s.prefix+SubSeparator+tag, discriminator...)
+	}
+
 	c, ok := any(conductor).(*tagged[T])
 	if !ok {
 		panic("not a conductor.Tagged")
@@ -166,10 +254,13 @@ func TaggedFromSimple[T any](s Conductor[T]) Conductor[T] {
 	if !ok {
 		panic("not a conductor.Simple")
 	}
+	c.tag = defaultTag
 	return &tagged[T]{
 		tagged: map[any]*simple[T]{
 			defaultTag: c,
 		},
+		mu:  new(sync.RWMutex),
 		ctx: c.ctx,
+		hub: c.hub,
 	}
 }
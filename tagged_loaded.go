@@ -44,3 +44,29 @@ func (l *loaded[T]) WithContext(ctx context.Context) Conductor[T] {
 func (l *loaded[T]) WithContextPolicy(policy Policy[T]) Conductor[T] {
 	return l.wrapped.WithContextPolicy(policy)
 }
+
+/* Internal functions */
+
+// unwrap returns the [Conductor] a loaded, a graceful or a sub one refers to, or the
+// given one as is.
+func unwrap[T any](conductor Conductor[T]) Conductor[T] {
+	switch c := any(conductor).(type) {
+	case *loaded[T]:
+		return c.wrapped
+	case *graceful[T]:
+		return unwrap(c.wrapped)
+	case *sub[T]:
+		return c.wrapped
+	default:
+		return conductor
+	}
+}
+
+// subOf returns the sub-conductor that the given [Conductor] is, or wraps.
+func subOf[T any](conductor Conductor[T]) (*sub[T], bool) {
+	if g, ok := any(conductor).(*graceful[T]); ok {
+		return subOf(g.wrapped)
+	}
+	s, ok := any(conductor).(*sub[T])
+	return s, ok
+}
@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"errors"
+	"sync"
+
+	"git.sr.ht/~blallo/conductor/internal/queue"
+)
+
+// ErrClosed is returned when using a closed [Transport].
+var ErrClosed = errors.New("transport closed")
+
+type subscription struct {
+	prefix  string
+	handler Handler
+
+	// XXX: the messages are queued, with no bound, and handed to the handler by a
+	// goroutine of its own, so that publishing never waits for a handler, which may
+	// itself be waiting for a publisher, e.g. one delivering to a conductor attached
+	// with Attach, whose publishing loop is blocked publishing back.
+	queue   *queue.Queue[delivery]
+	stopped chan struct{}
+	once    sync.Once
+}
+
+type delivery struct {
+	subject string
+	data    []byte
+}
+
+func newSubscription(prefix string, handler Handler) *subscription {
+	sub := &subscription{
+		prefix:  prefix,
+		handler: handler,
+		queue:   queue.New[delivery](),
+		stopped: make(chan struct{}),
+	}
+	go sub.run()
+	return sub
+}
+
+func (s *subscription) push(subject string, data []byte) {
+	s.queue.Push(delivery{subject: subject, data: data})
+}
+
+func (s *subscription) run() {
+	for {
+		d, ok := s.queue.Pop()
+		if !ok {
+			return
+		}
+		select {
+		case <-s.stopped:
+			return
+		default:
+		}
+		s.handler(d.subject, d.data)
+	}
+}
+
+func (s *subscription) stop() {
+	s.once.Do(func() {
+		close(s.stopped)
+		s.queue.Close()
+	})
+}
+
+// Memory is an in-process [Transport], useful to connect many conductors living in
+// the same process, and as a reference for the other implementations. Each subscription
+// gets the messages in the order they were published, from a goroutine of its own:
+// [Memory.Publish] never waits for the handlers.
+type Memory struct {
+	mu     sync.RWMutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+var _ Transport = &Memory{}
+
+// NewMemory creates a [Memory] transport.
+func NewMemory() *Memory {
+	return &Memory{
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+// Publish implements [Transport].
+func (m *Memory) Publish(subject string, data []byte) error {
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+
+	var matching []*subscription
+	for sub := range m.subs {
+		if below(subject, sub.prefix) {
+			matching = append(matching, sub)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, sub := range matching {
+		sub.push(subject, append([]byte(nil), data...))
+	}
+
+	return nil
+}
+
+// Subscribe implements [Transport].
+func (m *Memory) Subscribe(prefix string, handler Handler) (func() error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	sub := newSubscription(prefix, handler)
+	m.subs[sub] = struct{}{}
+
+	return func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		delete(m.subs, sub)
+		sub.stop()
+		return nil
+	}, nil
+}
+
+// Close implements [Transport].
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for sub := range m.subs {
+		sub.stop()
+	}
+	m.subs = make(map[*subscription]struct{})
+	return nil
+}
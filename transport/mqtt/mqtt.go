@@ -0,0 +1,382 @@
+// Package mqtt implements a [transport.Transport] on top of an MQTT 3.1.1 broker,
+// speaking the MQTT protocol with QoS 0.
+//
+// MQTT separates the levels of a topic with slashes, so the dots of the subjects are
+// mapped to slashes, and back: the subject "conductor.tag.db" is published on the topic
+// "conductor/tag/db".
+package mqtt
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"git.sr.ht/~blallo/conductor/transport"
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	keepAlive   = 30 * time.Second
+)
+
+// Packet types, see section 2.2.1 of the MQTT 3.1.1 specification.
+const (
+	packetConnect     byte = 1
+	packetConnack     byte = 2
+	packetPublish     byte = 3
+	packetSubscribe   byte = 8
+	packetSuback      byte = 9
+	packetUnsubscribe byte = 10
+	packetUnsuback    byte = 11
+	packetPingreq     byte = 12
+	packetPingresp    byte = 13
+	packetDisconnect  byte = 14
+)
+
+const subackFailure = 0x80
+
+// Transport is a [transport.Transport] backed by an MQTT broker.
+type Transport struct {
+	conn net.Conn
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu     sync.RWMutex
+	subs   map[uint16]*subscription
+	acks   map[uint16]chan []byte
+	nextID uint16
+	err    error
+	done   chan struct{}
+}
+
+var _ transport.Transport = &Transport{}
+
+type subscription struct {
+	filter  string
+	handler transport.Handler
+}
+
+// Dial connects to the MQTT broker at the given address (host:port), with a random
+// client identifier and a clean session.
+func Dial(address string) (*Transport, error) {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transport{
+		conn: conn,
+		w:    bufio.NewWriter(conn),
+		subs: make(map[uint16]*subscription),
+		acks: make(map[uint16]chan []byte),
+		done: make(chan struct{}),
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Protocol name, level 4 (3.1.1), clean session, keep alive and client id.
+	connect := appendString(nil, "MQTT")
+	connect = append(connect, 4, 0x02)
+	connect = binary.BigEndian.AppendUint16(connect, uint16(keepAlive/time.Second))
+	connect = appendString(connect, "conductor-"+hex.EncodeToString(id[:]))
+	if err := t.write(packetConnect, 0, connect); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	kind, _, body, err := readPacket(r)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if kind != packetConnack || len(body) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: unexpected packet %d while connecting", kind)
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: connection refused with code %d", body[1])
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	go t.read(r)
+	go t.ping()
+
+	return t, nil
+}
+
+// Publish implements [transport.Transport]. The subject must not be empty, nor hold
+// the "+" and "#" wildcards, otherwise an error wrapping
+// [transport.ErrInvalidSubject] is returned.
+func (t *Transport) Publish(subject string, data []byte) error {
+	if !valid(subject) {
+		return fmt.Errorf("mqtt: %w: %q", transport.ErrInvalidSubject, subject)
+	}
+
+	return t.write(packetPublish, 0, append(appendString(nil, topic(subject)), data...))
+}
+
+// Subscribe implements [transport.Transport]. It waits for the broker to accept the
+// subscription.
+func (t *Transport) Subscribe(prefix string, handler transport.Handler) (func() error, error) {
+	if !valid(prefix) {
+		return nil, fmt.Errorf("mqtt: %w: %q", transport.ErrInvalidSubject, prefix)
+	}
+
+	s := &subscription{
+		filter:  topic(prefix) + "/#",
+		handler: handler,
+	}
+
+	t.mu.Lock()
+	id := t.packetID()
+	t.subs[id] = s
+	t.mu.Unlock()
+
+	codes, err := t.request(packetSubscribe, id, append(appendString(nil, s.filter), 0))
+	if err == nil && (len(codes) != 1 || codes[0] == subackFailure) {
+		err = fmt.Errorf("mqtt: subscription to %s refused", s.filter)
+	}
+	if err != nil {
+		t.mu.Lock()
+		delete(t.subs, id)
+		t.mu.Unlock()
+		return nil, err
+	}
+
+	return func() error {
+		t.mu.Lock()
+		delete(t.subs, id)
+		unsubscribe := t.packetID()
+		t.mu.Unlock()
+
+		_, err := t.request(packetUnsubscribe, unsubscribe, appendString(nil, s.filter))
+		return err
+	}, nil
+}
+
+// Close implements [transport.Transport].
+func (t *Transport) Close() error {
+	t.write(packetDisconnect, 0, nil)
+	return t.conn.Close()
+}
+
+// valid tells if the subject can be used as a topic: MQTT gives a meaning to the "+"
+// and "#" characters, and forbids the null character.
+func valid(subject string) bool {
+	return subject != "" && !strings.ContainsAny(subject, "+#\x00")
+}
+
+func topic(subject string) string {
+	return strings.ReplaceAll(subject, ".", "/")
+}
+
+func subject(topic string) string {
+	return strings.ReplaceAll(topic, "/", ".")
+}
+
+// packetID returns the next packet identifier, that is never zero. It must be called
+// holding the lock.
+func (t *Transport) packetID() uint16 {
+	t.nextID++
+	if t.nextID == 0 {
+		t.nextID++
+	}
+	return t.nextID
+}
+
+// request sends a packet carrying the given identifier, and waits for its
+// acknowledgement, returning its payload.
+func (t *Transport) request(kind byte, id uint16, payload []byte) ([]byte, error) {
+	ack := make(chan []byte, 1)
+	t.mu.Lock()
+	t.acks[id] = ack
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.acks, id)
+		t.mu.Unlock()
+	}()
+
+	// XXX: the subscribe and unsubscribe packets have the reserved flags set to 2.
+	if err := t.write(kind, 0x02, append(binary.BigEndian.AppendUint16(nil, id), payload...)); err != nil {
+		return nil, err
+	}
+
+	select {
+	case body := <-ack:
+		return body, nil
+	case <-t.done:
+		return nil, t.closed()
+	case <-time.After(dialTimeout):
+		return nil, errors.New("mqtt: timeout waiting for the broker")
+	}
+}
+
+func (t *Transport) closed() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.err != nil {
+		return t.err
+	}
+	return net.ErrClosed
+}
+
+func (t *Transport) write(kind, flags byte, body []byte) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	t.w.WriteByte(kind<<4 | flags)
+	t.w.Write(appendLength(nil, len(body)))
+	t.w.Write(body)
+	return t.w.Flush()
+}
+
+func (t *Transport) ping() {
+	ticker := time.NewTicker(keepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.write(packetPingreq, 0, nil); err != nil {
+				return
+			}
+		case <-t.done:
+			return
+		}
+	}
+}
+
+func (t *Transport) read(r *bufio.Reader) {
+	err := t.loop(r)
+
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+	close(t.done)
+	t.conn.Close()
+}
+
+func (t *Transport) loop(r *bufio.Reader) error {
+	for {
+		kind, _, body, err := readPacket(r)
+		if err != nil {
+			return err
+		}
+
+		switch kind {
+		case packetPublish:
+			name, payload, err := readString(body)
+			if err != nil {
+				return err
+			}
+
+			t.mu.RLock()
+			var handlers []transport.Handler
+			for _, s := range t.subs {
+				if strings.HasPrefix(name, strings.TrimSuffix(s.filter, "#")) {
+					handlers = append(handlers, s.handler)
+				}
+			}
+			t.mu.RUnlock()
+
+			for _, handler := range handlers {
+				handler(subject(name), payload)
+			}
+		case packetSuback, packetUnsuback:
+			if len(body) < 2 {
+				return fmt.Errorf("mqtt: malformed acknowledgement")
+			}
+			id := binary.BigEndian.Uint16(body)
+
+			t.mu.RLock()
+			ack, ok := t.acks[id]
+			t.mu.RUnlock()
+			if ok {
+				ack <- body[2:]
+			}
+		case packetPingresp:
+		default:
+			return fmt.Errorf("mqtt: unexpected packet %d", kind)
+		}
+	}
+}
+
+// readPacket reads a control packet, returning its type, its flags and its body.
+func readPacket(r *bufio.Reader) (byte, byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	// XXX: the remaining length is encoded in at most four bytes, seven bits each.
+	var size int
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		size |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, 0, nil, err
+	}
+
+	return header >> 4, header & 0x0f, body, nil
+}
+
+func appendLength(buf []byte, size int) []byte {
+	for {
+		b := byte(size & 0x7f)
+		size >>= 7
+		if size > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if size == 0 {
+			return buf
+		}
+	}
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// readString reads a length prefixed string, returning what follows it.
+func readString(body []byte) (string, []byte, error) {
+	if len(body) < 2 {
+		return "", nil, errors.New("mqtt: malformed string")
+	}
+	size := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+size {
+		return "", nil, errors.New("mqtt: malformed string")
+	}
+	return string(body[2 : 2+size]), body[2+size:], nil
+}
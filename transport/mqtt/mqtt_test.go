@@ -0,0 +1,172 @@
+package mqtt
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"git.sr.ht/~blallo/conductor/transport"
+	"git.sr.ht/~blallo/conductor/transport/transporttest"
+)
+
+// server is a stand-in for an MQTT broker, supporting just what the Transport uses.
+type server struct {
+	l    net.Listener
+	mu   sync.Mutex
+	subs map[*client]map[string]struct{}
+}
+
+type client struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (c *client) send(kind byte, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	packet := appendLength([]byte{kind << 4}, len(body))
+	c.conn.Write(append(packet, body...))
+}
+
+func newServer(t *testing.T) *server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &server{
+		l:    l,
+		subs: make(map[*client]map[string]struct{}),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	c := &client{conn: conn}
+	s.mu.Lock()
+	s.subs[c] = make(map[string]struct{})
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, c)
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		kind, _, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+
+		switch kind {
+		case packetConnect:
+			c.send(packetConnack, []byte{0, 0})
+		case packetPingreq:
+			c.send(packetPingresp, nil)
+		case packetSubscribe, packetUnsubscribe:
+			filter, _, err := readString(body[2:])
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			if kind == packetSubscribe {
+				s.subs[c][filter] = struct{}{}
+			} else {
+				delete(s.subs[c], filter)
+			}
+			s.mu.Unlock()
+
+			if kind == packetSubscribe {
+				c.send(packetSuback, append(body[:2:2], 0))
+			} else {
+				c.send(packetUnsuback, body[:2])
+			}
+		case packetPublish:
+			name, _, err := readString(body)
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			for other, filters := range s.subs {
+				for filter := range filters {
+					if strings.HasPrefix(name, strings.TrimSuffix(filter, "#")) {
+						other.send(packetPublish, body)
+					}
+				}
+			}
+			s.mu.Unlock()
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	s := newServer(t)
+
+	ta, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ta.Close()
+
+	tb, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	transporttest.Run(t, ta, tb)
+}
+
+func TestTransport_invalidSubject(t *testing.T) {
+	s := newServer(t)
+
+	tr, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	for _, subject := range []string{"", "app.tag.+", "app.tag.#"} {
+		if err := tr.Publish(subject, nil); !errors.Is(err, transport.ErrInvalidSubject) {
+			t.Fatalf("Unexpected error publishing on %q: %v", subject, err)
+		}
+	}
+	if err := tr.Publish("app.tag.db", nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLength(t *testing.T) {
+	for _, size := range []int{0, 127, 128, 16383, 16384, 2097152} {
+		buf := appendLength([]byte{packetPingreq << 4}, size)
+		buf = append(buf, make([]byte, size)...)
+
+		kind, _, body, err := readPacket(bufio.NewReader(strings.NewReader(string(buf))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind != packetPingreq || len(body) != size {
+			t.Fatalf("Unexpected packet %d of %d bytes, expected %d", kind, len(body), size)
+		}
+	}
+}
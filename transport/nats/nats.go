@@ -0,0 +1,210 @@
+// Package nats implements a [transport.Transport] on top of a NATS server, speaking
+// the core NATS text protocol.
+package nats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.sr.ht/~blallo/conductor/transport"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Transport is a [transport.Transport] backed by a NATS server.
+type Transport struct {
+	conn net.Conn
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu      sync.RWMutex
+	subs    map[int]transport.Handler
+	nextSid int
+	pong    chan struct{}
+	err     error
+	done    chan struct{}
+}
+
+var _ transport.Transport = &Transport{}
+
+// Dial connects to the NATS server at the given address (host:port).
+func Dial(address string) (*Transport, error) {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transport{
+		conn: conn,
+		w:    bufio.NewWriter(conn),
+		subs: make(map[int]transport.Handler),
+		pong: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	info, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(info, "INFO") {
+		conn.Close()
+		return nil, fmt.Errorf("nats: unexpected greeting: %q", info)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	go t.read(r)
+
+	if err := t.write("CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"conductor\"}\r\nPING\r\n"); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	select {
+	case <-t.pong:
+	case <-t.done:
+		return nil, t.err
+	case <-time.After(dialTimeout):
+		t.Close()
+		return nil, errors.New("nats: timeout waiting for the server")
+	}
+
+	return t, nil
+}
+
+// Publish implements [transport.Transport]. The subject must not be empty, nor hold
+// whitespace, wildcards or empty tokens, otherwise an error wrapping
+// [transport.ErrInvalidSubject] is returned.
+func (t *Transport) Publish(subject string, data []byte) error {
+	if !valid(subject) {
+		return fmt.Errorf("nats: %w: %q", transport.ErrInvalidSubject, subject)
+	}
+
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	fmt.Fprintf(t.w, "PUB %s %d\r\n", subject, len(data))
+	t.w.Write(data)
+	t.w.WriteString("\r\n")
+	return t.w.Flush()
+}
+
+// Subscribe implements [transport.Transport].
+func (t *Transport) Subscribe(prefix string, handler transport.Handler) (func() error, error) {
+	if !valid(prefix) {
+		return nil, fmt.Errorf("nats: %w: %q", transport.ErrInvalidSubject, prefix)
+	}
+
+	t.mu.Lock()
+	t.nextSid++
+	sid := t.nextSid
+	t.subs[sid] = handler
+	t.mu.Unlock()
+
+	if err := t.write(fmt.Sprintf("SUB %s.> %d\r\n", prefix, sid)); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		t.mu.Lock()
+		delete(t.subs, sid)
+		t.mu.Unlock()
+
+		return t.write(fmt.Sprintf("UNSUB %d\r\n", sid))
+	}, nil
+}
+
+// Close implements [transport.Transport].
+func (t *Transport) Close() error {
+	return t.conn.Close()
+}
+
+// valid tells if the subject can be used literally: NATS splits the protocol lines on
+// whitespace, and gives a meaning to the "*" and ">" tokens.
+func valid(subject string) bool {
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" || strings.ContainsAny(token, " \t\r\n*>") {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Transport) write(s string) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	t.w.WriteString(s)
+	return t.w.Flush()
+}
+
+func (t *Transport) read(r *bufio.Reader) {
+	err := t.loop(r)
+
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+	close(t.done)
+	t.conn.Close()
+}
+
+func (t *Transport) loop(r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		op, args, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(op) {
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(args)
+			if len(fields) < 3 {
+				return fmt.Errorf("nats: malformed MSG: %q", line)
+			}
+			sid, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return fmt.Errorf("nats: malformed MSG: %q", line)
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return fmt.Errorf("nats: malformed MSG: %q", line)
+			}
+
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+
+			t.mu.RLock()
+			handler, ok := t.subs[sid]
+			t.mu.RUnlock()
+			if ok {
+				handler(fields[0], data[:size])
+			}
+		case "PING":
+			if err := t.write("PONG\r\n"); err != nil {
+				return err
+			}
+		case "PONG":
+			select {
+			case t.pong <- struct{}{}:
+			default:
+			}
+		case "-ERR":
+			return fmt.Errorf("nats: %s", args)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package nats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"git.sr.ht/~blallo/conductor/transport"
+	"git.sr.ht/~blallo/conductor/transport/transporttest"
+)
+
+// server is a stand-in for a NATS server, supporting just what the Transport uses.
+type server struct {
+	l    net.Listener
+	mu   sync.Mutex
+	subs map[*client]map[string]string
+}
+
+type client struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func (c *client) send(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(c.w, format, args...)
+	c.w.Flush()
+}
+
+func newServer(t *testing.T) *server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &server{
+		l:    l,
+		subs: make(map[*client]map[string]string),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func matches(pattern, subject string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, ".>"); ok {
+		return strings.HasPrefix(subject, prefix+".")
+	}
+	return pattern == subject
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	c := &client{w: bufio.NewWriter(conn)}
+	s.mu.Lock()
+	s.subs[c] = make(map[string]string)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, c)
+		s.mu.Unlock()
+	}()
+
+	c.send("INFO {\"server_id\":\"stand-in\"}\r\n")
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "PING":
+			c.send("PONG\r\n")
+		case "SUB":
+			s.mu.Lock()
+			s.subs[c][fields[2]] = fields[1]
+			s.mu.Unlock()
+		case "UNSUB":
+			s.mu.Lock()
+			delete(s.subs[c], fields[1])
+			s.mu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			for other, subs := range s.subs {
+				for sid, pattern := range subs {
+					if matches(pattern, fields[1]) {
+						other.send("MSG %s %s %d\r\n%s", fields[1], sid, size, data)
+					}
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	s := newServer(t)
+
+	ta, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ta.Close()
+
+	tb, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	transporttest.Run(t, ta, tb)
+}
+
+func TestTransport_invalidSubject(t *testing.T) {
+	s := newServer(t)
+
+	tr, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	for _, subject := range []string{"", "app.tag.two words", "app.tag.*", "app.tag.>", "app..all"} {
+		if err := tr.Publish(subject, nil); !errors.Is(err, transport.ErrInvalidSubject) {
+			t.Fatalf("Unexpected error publishing on %q: %v", subject, err)
+		}
+	}
+	if err := tr.Publish("app.tag.db", nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,309 @@
+// Package redis implements a [transport.Transport] on top of Redis pub/sub, speaking
+// the RESP protocol.
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.sr.ht/~blallo/conductor/transport"
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	minBackoff  = 100 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+)
+
+// Transport is a [transport.Transport] backed by a Redis server. Since a connection
+// in subscribed state cannot publish, each subscription uses its own connection.
+type Transport struct {
+	address string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+
+	subsMu  sync.Mutex
+	subs    map[*subscription]struct{}
+	onError func(error)
+}
+
+var _ transport.Transport = &Transport{}
+
+// subscription is a pattern subscription, holding its current connection.
+type subscription struct {
+	pattern string
+	handler transport.Handler
+	done    chan struct{}
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// Dial connects to the Redis server at the given address (host:port).
+func Dial(address string) (*Transport, error) {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transport{
+		address: address,
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		subs:    make(map[*subscription]struct{}),
+	}, nil
+}
+
+// OnError sets the function called with the errors losing the connection of a
+// subscription, and with the ones of the attempts to subscribe again. By default they
+// are ignored.
+func (t *Transport) OnError(fn func(error)) {
+	t.subsMu.Lock()
+	defer t.subsMu.Unlock()
+
+	t.onError = fn
+}
+
+// Publish implements [transport.Transport].
+func (t *Transport) Publish(subject string, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := writeCommand(t.conn, []byte("PUBLISH"), []byte(subject), data); err != nil {
+		return err
+	}
+
+	_, err := readValue(t.r)
+	return err
+}
+
+// Subscribe implements [transport.Transport]. Whenever the connection of the
+// subscription is lost, it is dialed again with an exponential backoff, until the
+// subscription is canceled or the Transport closed. The messages published meanwhile
+// are lost. The characters of the prefix that Redis takes as wildcards are escaped.
+func (t *Transport) Subscribe(prefix string, handler transport.Handler) (func() error, error) {
+	s := &subscription{
+		pattern: escape(prefix) + ".*",
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+
+	r, err := t.subscribe(s)
+	if err != nil {
+		return nil, err
+	}
+
+	t.subsMu.Lock()
+	t.subs[s] = struct{}{}
+	t.subsMu.Unlock()
+
+	go t.receive(s, r)
+
+	return func() error {
+		t.subsMu.Lock()
+		delete(t.subs, s)
+		t.subsMu.Unlock()
+
+		return s.close()
+	}, nil
+}
+
+// Close implements [transport.Transport].
+func (t *Transport) Close() error {
+	t.subsMu.Lock()
+	for s := range t.subs {
+		s.close()
+	}
+	t.subs = make(map[*subscription]struct{})
+	t.subsMu.Unlock()
+
+	return t.conn.Close()
+}
+
+// subscribe dials a connection for the subscription, and subscribes to its pattern.
+func (t *Transport) subscribe(s *subscription) (*bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", t.address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	if err := writeCommand(conn, []byte("PSUBSCRIBE"), []byte(s.pattern)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := readValue(r); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		conn.Close()
+		return nil, net.ErrClosed
+	}
+	s.conn = conn
+
+	return r, nil
+}
+
+// receive hands the messages of the subscription over to its handler, subscribing
+// again whenever the connection is lost.
+func (t *Transport) receive(s *subscription, r *bufio.Reader) {
+	for {
+		err := s.read(r)
+
+		backoff := minBackoff
+		for {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			t.report(fmt.Errorf("redis: subscription to %s: %w", s.pattern, err))
+
+			select {
+			case <-s.done:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxBackoff)
+
+			if r, err = t.subscribe(s); err == nil {
+				break
+			}
+		}
+	}
+}
+
+func (t *Transport) report(err error) {
+	t.subsMu.Lock()
+	onError := t.onError
+	t.subsMu.Unlock()
+
+	if onError != nil {
+		onError(err)
+	}
+}
+
+// read hands the messages over to the handler, until the connection is lost.
+func (s *subscription) read(r *bufio.Reader) error {
+	for {
+		value, err := readValue(r)
+		if err != nil {
+			return err
+		}
+		// ["pmessage", pattern, channel, data]
+		msg, ok := value.([]any)
+		if !ok || len(msg) != 4 {
+			continue
+		}
+		if kind, _ := msg[0].([]byte); string(kind) != "pmessage" {
+			continue
+		}
+		channel, _ := msg[2].([]byte)
+		data, _ := msg[3].([]byte)
+		s.handler(string(channel), data)
+	}
+}
+
+func (s *subscription) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	return s.conn.Close()
+}
+
+func writeCommand(w io.Writer, args ...[]byte) error {
+	buf := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, arg := range args {
+		buf = fmt.Appendf(buf, "$%d\r\n", len(arg))
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// readValue reads a RESP value: simple strings are returned as string, bulk strings
+// as []byte, integers as int64 and arrays as []any. Errors are returned as error.
+func readValue(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: malformed reply: %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, errors.New("redis: " + payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		values := make([]any, size)
+		for i := range values {
+			if values[i], err = readValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type: %q", line)
+	}
+}
+
+// escape quotes the characters of the given string that a PSUBSCRIBE pattern takes as
+// wildcards, so that it only matches itself.
+func escape(literal string) string {
+	var b strings.Builder
+	for _, r := range literal {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
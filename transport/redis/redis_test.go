@@ -0,0 +1,230 @@
+package redis
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor/transport/transporttest"
+)
+
+// server is a stand-in for a Redis server, supporting just what the Transport uses.
+type server struct {
+	l    net.Listener
+	mu   sync.Mutex
+	subs map[net.Conn]string
+}
+
+func newServer(t *testing.T) *server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &server{
+		l:    l,
+		subs: make(map[net.Conn]string),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, conn)
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		value, err := readValue(r)
+		if err != nil {
+			return
+		}
+		args, ok := value.([]any)
+		if !ok || len(args) == 0 {
+			return
+		}
+		cmd, _ := args[0].([]byte)
+
+		switch strings.ToUpper(string(cmd)) {
+		case "PSUBSCRIBE":
+			pattern := args[1].([]byte)
+			s.mu.Lock()
+			s.subs[conn] = string(pattern)
+			s.mu.Unlock()
+			writeCommand(conn, []byte("psubscribe"), pattern, []byte("1"))
+		case "PUBLISH":
+			channel, data := args[1].([]byte), args[2].([]byte)
+			s.mu.Lock()
+			for sub, pattern := range s.subs {
+				if match(pattern, string(channel)) {
+					writeCommand(sub, []byte("pmessage"), []byte(pattern), channel, data)
+				}
+			}
+			s.mu.Unlock()
+			conn.Write([]byte(":1\r\n"))
+		}
+	}
+}
+
+// match tells if the channel matches the glob-style pattern, as Redis does.
+func match(pattern, channel string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(channel); i >= 0; i-- {
+				if match(pattern[1:], channel[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(channel) == 0 {
+				return false
+			}
+		case '[':
+			end := strings.IndexByte(pattern, ']')
+			if end < 0 || len(channel) == 0 || !strings.ContainsRune(pattern[1:end], rune(channel[0])) {
+				return false
+			}
+			pattern, channel = pattern[end+1:], channel[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(channel) == 0 || pattern[0] != channel[0] {
+				return false
+			}
+		}
+		pattern, channel = pattern[1:], channel[1:]
+	}
+	return len(channel) == 0
+}
+
+func TestTransport(t *testing.T) {
+	s := newServer(t)
+
+	ta, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ta.Close()
+
+	tb, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	transporttest.Run(t, ta, tb)
+}
+
+func TestTransport_resubscribes(t *testing.T) {
+	s := newServer(t)
+
+	tr, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	errs := make(chan error, 10)
+	tr.OnError(func(err error) { errs <- err })
+
+	received := make(chan string, 10)
+	unsubscribe, err := tr.Subscribe("app", func(subject string, _ []byte) {
+		received <- subject
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	s.mu.Lock()
+	for conn := range s.subs {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("Lost subscription not reported")
+	}
+
+	deadline := time.After(time.Second)
+	for {
+		if err := tr.Publish("app.all", nil); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case subject := <-received:
+			if subject != "app.all" {
+				t.Fatalf("Unexpected subject: %s", subject)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("Not subscribed again")
+		}
+	}
+}
+
+func TestTransport_escapesPrefix(t *testing.T) {
+	s := newServer(t)
+
+	tr, err := Dial(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	received := make(chan string, 10)
+	unsubscribe, err := tr.Subscribe("app[1]*", func(subject string, _ []byte) {
+		received <- subject
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	// Unescaped, the prefix would match this subject too.
+	for _, subject := range []string{"app1x.all", "app[1]*.all"} {
+		if err := tr.Publish(subject, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case subject := <-received:
+		if subject != "app[1]*.all" {
+			t.Fatalf("Unexpected subject: %s", subject)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout")
+	}
+	select {
+	case subject := <-received:
+		t.Fatalf("Unexpected subject: %s", subject)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
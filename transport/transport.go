@@ -0,0 +1,218 @@
+// Package transport lets a [conductor.Conductor] publish its commands to, and receive
+// commands from, an external message broker, so that a command sent on a tag fans out
+// to every process attached to the same broker.
+//
+// Each command is published on a subject derived from its tags: a command sent to
+// the tag "db" goes to "<prefix>.tag.db", while a broadcast goes to "<prefix>.all".
+// Subjects are hierarchical, with the dot as separator.
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+	"git.sr.ht/~blallo/conductor/internal/seen"
+)
+
+const (
+	originPrefix  = "transport:"
+	defaultPrefix = "conductor"
+	seenSize      = 4096
+)
+
+// ErrInvalidSubject is the error wrapped by a [Transport] refusing to publish on a
+// subject the broker does not accept, e.g. one derived from a tag holding spaces.
+var ErrInvalidSubject = errors.New("invalid subject")
+
+// Handler receives the messages a [Transport] subscription matches.
+type Handler func(subject string, data []byte)
+
+// Transport abstracts a publish/subscribe message broker.
+type Transport interface {
+	// Publish sends data on the given subject.
+	Publish(subject string, data []byte) error
+	// Subscribe calls handler for every message published on a subject below the
+	// given prefix, i.e. starting with the prefix followed by a dot. The returned
+	// function cancels the subscription.
+	Subscribe(prefix string, handler Handler) (func() error, error)
+	// Close releases the resources held by the Transport.
+	Close() error
+}
+
+// Config tunes how a [conductor.Conductor] is attached to a [Transport]. The zero
+// value is usable.
+type Config[T any] struct {
+	// Codec is used to encode the commands. Defaults to [conductor.JSONCodec].
+	Codec conductor.Codec[T]
+	// Prefix is prepended to all the subjects. Defaults to "conductor".
+	Prefix string
+	// Node identifies this process. Defaults to the hostname and pid.
+	Node string
+	// OnError is called with the errors of the commands that cannot be published,
+	// because they fail to encode or to map to a valid subject, and are skipped.
+	// Defaults to ignoring them.
+	OnError func(error)
+}
+
+// message is what gets published on the broker.
+type message struct {
+	ID   string            `json:"id"`
+	Node string            `json:"node"`
+	Tags []string          `json:"tags,omitempty"`
+	Time time.Time         `json:"time"`
+	Meta map[string]string `json:"meta,omitempty"`
+	Cmd  []byte            `json:"cmd"`
+}
+
+// Subjects returns the subjects a command sent to the given tags is published on,
+// given the subject prefix.
+func Subjects(prefix string, tags ...string) []string {
+	if len(tags) == 0 {
+		return []string{prefix + ".all"}
+	}
+
+	subjects := make([]string, len(tags))
+	for i, tag := range tags {
+		subjects[i] = prefix + ".tag." + tag
+	}
+	return subjects
+}
+
+// Attach publishes on the [Transport] the commands sent on the given
+// [conductor.Conductor], and delivers to it the commands published by the other
+// processes attached to the same broker. A command sent to many tags is published
+// once per tag, but delivered only once. No command is dropped: a slow broker slows the
+// senders down. Attach blocks until the conductor is done, or until publishing fails,
+// except for the commands reported to [Config.OnError].
+func Attach[T any](c conductor.Conductor[T], t Transport, cfg Config[T]) error {
+	if cfg.Codec == nil {
+		cfg.Codec = conductor.JSONCodec[T]()
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultPrefix
+	}
+	if cfg.Node == "" {
+		hostname, _ := os.Hostname()
+		cfg.Node = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(error) {}
+	}
+
+	delivered := seen.New(seenSize)
+
+	// XXX: follow before subscribing, so that what gets delivered from the broker
+	// is seen, and skipped, by the publishing loop below.
+	envelopes, stop := conductor.Follow(c)
+	defer stop()
+
+	unsubscribe, err := t.Subscribe(cfg.Prefix, func(subject string, data []byte) {
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		if msg.Node == cfg.Node || !delivered.Add(msg.ID) {
+			return
+		}
+
+		cmd, err := cfg.Codec.Decode(msg.Cmd)
+		if err != nil {
+			return
+		}
+
+		env := conductor.Envelope[T]{
+			ID:     msg.ID,
+			Cmd:    cmd,
+			Time:   msg.Time,
+			Origin: originPrefix + msg.Node,
+			Meta:   msg.Meta,
+		}
+		for _, tag := range msg.Tags {
+			env.Tags = append(env.Tags, tag)
+		}
+		conductor.Deliver(c, env)
+	})
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case env := <-envelopes:
+			// Only the commands sent by this process are published.
+			if env.Origin != "" {
+				continue
+			}
+			if err := publish(t, cfg, env); err != nil {
+				var skipped *skippedError
+				if !errors.As(err, &skipped) {
+					return err
+				}
+				cfg.OnError(skipped.err)
+			}
+		case <-c.Done():
+			return nil
+		}
+	}
+}
+
+// skippedError wraps the error of a command that cannot be published, while the
+// [Transport] is still usable.
+type skippedError struct {
+	err error
+}
+
+func (e *skippedError) Error() string {
+	return e.err.Error()
+}
+
+func publish[T any](t Transport, cfg Config[T], env conductor.Envelope[T]) error {
+	data, err := cfg.Codec.Encode(env.Cmd)
+	if err != nil {
+		return &skippedError{err: fmt.Errorf("cannot encode %v: %w", env.Cmd, err)}
+	}
+
+	msg := message{
+		ID:   env.ID,
+		Node: cfg.Node,
+		Time: env.Time,
+		Meta: env.Meta,
+		Cmd:  data,
+	}
+	for _, tag := range env.Tags {
+		msg.Tags = append(msg.Tags, fmt.Sprint(tag))
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	var invalid []error
+	for _, subject := range Subjects(cfg.Prefix, msg.Tags...) {
+		err := t.Publish(subject, payload)
+		if errors.Is(err, ErrInvalidSubject) {
+			invalid = append(invalid, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(invalid) > 0 {
+		return &skippedError{err: errors.Join(invalid...)}
+	}
+
+	return nil
+}
+
+// below tells whether the subject is below the given prefix.
+func below(subject, prefix string) bool {
+	return strings.HasPrefix(subject, prefix+".")
+}
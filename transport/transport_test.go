@@ -0,0 +1,131 @@
+package transport_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+	"git.sr.ht/~blallo/conductor/transport"
+	"git.sr.ht/~blallo/conductor/transport/transporttest"
+)
+
+func TestMemory(t *testing.T) {
+	m := transport.NewMemory()
+	defer m.Close()
+
+	transporttest.Run(t, m, m)
+}
+
+func TestSubjects(t *testing.T) {
+	if s := transport.Subjects("app"); len(s) != 1 || s[0] != "app.all" {
+		t.Fatalf("Unexpected subjects: %v", s)
+	}
+
+	s := transport.Subjects("app", "db", "web")
+	if len(s) != 2 || s[0] != "app.tag.db" || s[1] != "app.tag.web" {
+		t.Fatalf("Unexpected subjects: %v", s)
+	}
+}
+
+func TestAttach_skipsUnencodable(t *testing.T) {
+	m := transport.NewMemory()
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := conductor.SimpleFromContext[any](ctx)
+	b := conductor.SimpleFromContext[any](ctx)
+	lis := b.Cmd()
+
+	errs := make(chan error, 1)
+	attached := make(chan error, 2)
+	go func() {
+		attached <- transport.Attach(a, m, transport.Config[any]{
+			Node:    "a",
+			OnError: func(err error) { errs <- err },
+		})
+	}()
+	go func() { attached <- transport.Attach(b, m, transport.Config[any]{Node: "b"}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	conductor.Send(a)(func() {})
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("Unencodable command not reported")
+	}
+
+	conductor.Send(a)("ok")
+	select {
+	case cmd := <-lis:
+		if cmd != "ok" {
+			t.Fatalf("Unexpected command: %v", cmd)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Attach stopped publishing")
+	}
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		if err := <-attached; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAttach_twoWayLoad(t *testing.T) {
+	const n = 5000
+
+	m := transport.NewMemory()
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := conductor.SimpleFromContext[int](ctx)
+	b := conductor.SimpleFromContext[int](ctx)
+	lisA := a.Cmd()
+	lisB := b.Cmd()
+
+	attached := make(chan error, 2)
+	go func() { attached <- transport.Attach(a, m, transport.Config[int]{Node: "a"}) }()
+	go func() { attached <- transport.Attach(b, m, transport.Config[int]{Node: "b"}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// Each side receives its own commands and the ones of the other side.
+	received := make(chan int, 2)
+	count := func(lis <-chan int) {
+		for i := 0; i < 2*n; i++ {
+			select {
+			case <-lis:
+			case <-time.After(5 * time.Second):
+				received <- i
+				return
+			}
+		}
+		received <- 2 * n
+	}
+	go count(lisA)
+	go count(lisB)
+
+	for _, c := range []conductor.Conductor[int]{a, b} {
+		go func(c conductor.Conductor[int]) {
+			for i := 0; i < n; i++ {
+				conductor.Send(c)(i)
+			}
+		}(c)
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := <-received; got != 2*n {
+			t.Fatalf("Stalled after %d commands of %d", got, 2*n)
+		}
+	}
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		if err := <-attached; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
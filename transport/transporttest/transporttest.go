@@ -0,0 +1,86 @@
+// Package transporttest checks that an implementation of [transport.Transport]
+// behaves as expected.
+package transporttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.sr.ht/~blallo/conductor"
+	"git.sr.ht/~blallo/conductor/transport"
+)
+
+const (
+	failureTimeout = time.Second
+	successTimeout = 100 * time.Millisecond
+)
+
+// Run attaches a conductor to each of the given transports, that must be connected to
+// the same broker, and checks that they exchange commands as if they were the same.
+func Run(t *testing.T, ta, tb transport.Transport) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := conductor.TaggedFromContext[string](ctx)
+	b := conductor.TaggedFromContext[string](ctx)
+
+	dbA := conductor.WithTag(a, "db").Cmd()
+	dbB := conductor.WithTag(b, "db").Cmd()
+	webB := conductor.WithTag(b, "web").Cmd()
+	allB := b.Cmd()
+
+	attached := make(chan error, 2)
+	go func() { attached <- transport.Attach(a, ta, transport.Config[string]{Node: "a"}) }()
+	go func() { attached <- transport.Attach(b, tb, transport.Config[string]{Node: "b"}) }()
+
+	// XXX: give the time to subscribe.
+	time.Sleep(successTimeout)
+
+	conductor.Send(a, "db", "web")("pause")
+	expect(t, dbA, "pause")
+	expect(t, dbB, "pause")
+	expect(t, webB, "pause")
+	expect(t, allB, "pause")
+	expectNothing(t, allB)
+
+	conductor.Send(b)("stop")
+	expect(t, dbA, "stop")
+	expectNothing(t, dbA)
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-attached:
+			if err != nil {
+				t.Fatalf("Attach failed: %s", err)
+			}
+		case <-time.After(failureTimeout):
+			t.Fatal("Timeout waiting for Attach to return")
+		}
+	}
+}
+
+func expect(t *testing.T, lis <-chan string, want string) {
+	t.Helper()
+
+	select {
+	case cmd := <-lis:
+		if cmd != want {
+			t.Fatalf("Unexpected cmd: %s", cmd)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Timeout")
+	}
+}
+
+func expectNothing(t *testing.T, lis <-chan string) {
+	t.Helper()
+
+	select {
+	case cmd := <-lis:
+		t.Fatalf("Unexpected cmd: %s", cmd)
+	case <-time.After(successTimeout):
+	}
+}
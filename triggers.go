@@ -0,0 +1,318 @@
+package conductor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OriginTrigger is the origin of the commands fired by a [Policy] attached to a
+// [Trigger] with [On].
+const OriginTrigger = "trigger"
+
+// Trigger tells when a [Policy] attached with [On] has to be invoked, other than on
+// the cancellation of the [Conductor]. Start watches the given [Conductor] until stop
+// gets closed, and returns a channel yielding an event each time the [Policy] has to
+// fire: the event is the tag the [Policy] is asked about, or nil to fire to all the
+// listeners.
+type Trigger[T any] interface {
+	Start(conductor Conductor[T], stop <-chan struct{}) <-chan any
+}
+
+// TriggerFunc adapts a function to the [Trigger] interface.
+type TriggerFunc[T any] func(conductor Conductor[T], stop <-chan struct{}) <-chan any
+
+func (f TriggerFunc[T]) Start(conductor Conductor[T], stop <-chan struct{}) <-chan any {
+	return f(conductor, stop)
+}
+
+// On invokes the given [Policy] each time the given [Trigger] fires, until the returned
+// function is called or the [Conductor] is canceled. The [Policy] is invoked with the
+// tag of the event as argument, or with no argument if the event has no tag, and the
+// commands it decides are sent in the same way as [Send] does, i.e. to the listeners of
+// the tag and to the ones without a tag, going through the send [Interceptor]s. A
+// [MultiPolicy] gets its steps fired in order.
+func On[T any](conductor Conductor[T], trigger Trigger[T], policy Policy[T]) func() {
+	stop := make(chan struct{})
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() { close(stop) })
+	}
+
+	events := trigger.Start(conductor, stop)
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-conductor.Done():
+				return
+			case <-stop:
+				return
+			case tag, ok := <-events:
+				if !ok {
+					return
+				}
+				var tags []any
+				if tag != nil {
+					tags = []any{tag}
+				}
+				if steps, ok := decideSteps(policy, nil, nil, tags...); ok {
+					fireTrigger(conductor, tags, steps)
+				}
+			}
+		}
+	}()
+
+	return cancel
+}
+
+func fireTrigger[T any](conductor Conductor[T], tags []any, steps []Step[T]) {
+	for _, step := range steps {
+		if step.Delay > 0 {
+			time.Sleep(step.Delay)
+		}
+
+		target := triggerTarget(conductor)
+		env := newEnvelope(step.Cmd, tags, OriginTrigger)
+		send := func() error {
+			err := envelopeSender(target)(env)
+			if err != nil {
+				rejected(env.Cmd, err)
+			}
+			return err
+		}
+
+		if step.AckTimeout <= 0 {
+			send()
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), step.AckTimeout)
+		if _, err := collect(ctx, channels(target, tags), send); err != nil {
+			fmt.Fprintf(logFile, "Not all listeners acknowledged %s: %s\n", fmtCmd(step.Cmd), err)
+		}
+		cancel()
+	}
+}
+
+// triggerTarget returns the [Conductor] the commands of a [Trigger] are sent through:
+// a sub-conductor itself, as the tags of its events are the names within its
+// namespace, or else the wrapped one, as the tags of its events are its own.
+func triggerTarget[T any](conductor Conductor[T]) Conductor[T] {
+	if s, ok := subOf(conductor); ok {
+		return s
+	}
+	return unwrap(conductor)
+}
+
+// triggerScope returns the function telling if a tag of the wrapped conductor concerns
+// a [Trigger] started on the given [Conductor], and what it is called there: for a
+// sub-conductor, only the tags of its namespace do, by their name within it.
+func triggerScope[T any](conductor Conductor[T]) func(tag any) (any, bool) {
+	if s, ok := subOf(conductor); ok {
+		return s.scope
+	}
+	return func(tag any) (any, bool) {
+		return tag, true
+	}
+}
+
+// triggered tells if an event about the given tag concerns a [Trigger] watching the
+// given tags. The listeners without a tag of a Tagged [Conductor] never trigger.
+func triggered(tag any, tags []any) bool {
+	if tag == defaultTag {
+		return false
+	}
+	if len(tags) == 0 {
+		return true
+	}
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// lifecycleTrigger fires for each lifecycle event about the given tags for which the
+// match function returns true.
+func lifecycleTrigger[T any](match func(lifecycleEvent) bool, tags []any) Trigger[T] {
+	return TriggerFunc[T](func(conductor Conductor[T], stop <-chan struct{}) <-chan any {
+		lifecycle, unsubscribe := hubOf(conductor).subscribe()
+		scope := triggerScope(conductor)
+		out := make(chan any)
+
+		go func() {
+			defer close(out)
+			defer unsubscribe()
+
+			for {
+				select {
+				case <-stop:
+					return
+				case ev := <-lifecycle:
+					tag, ok := scope(ev.tag)
+					if !ok || !triggered(tag, tags) || !match(ev) {
+						continue
+					}
+					select {
+					case out <- tag:
+					case <-stop:
+						return
+					}
+				}
+			}
+		}()
+
+		return out
+	})
+}
+
+// ListenersGone is a [Trigger] firing when the last listener of a tag goes away, either
+// released with [Release] or evicted (see [EvictSlow]). With no tags, every tag is
+// watched. For a Simple [Conductor], it fires when its last listener goes away. For a
+// sub-conductor (see [Sub]), the tags are the names within its namespace, and the ones
+// outside of it are never watched.
+func ListenersGone[T any](tags ...any) Trigger[T] {
+	return lifecycleTrigger[T](func(ev lifecycleEvent) bool {
+		return ev.remaining == 0
+	}, tags)
+}
+
+// Evicted is a [Trigger] firing each time a listener of one of the given tags, or of any
+// tag if none is given, gets evicted for being too slow (see [EvictSlow]).
+func Evicted[T any](tags ...any) Trigger[T] {
+	return lifecycleTrigger[T](func(ev lifecycleEvent) bool {
+		return ev.kind == listenerEvicted
+	}, tags)
+}
+
+// IdleFor is a [Trigger] firing when no command was sent to one of the given tags, or to
+// any tag having listeners if none is given, for the given duration. It fires once per
+// idle period: the next one starts with the next command sent to the tag. The commands
+// fired by [On] do not count as activity. For a sub-conductor (see [Sub]), the tags are
+// the names within its namespace.
+func IdleFor[T any](d time.Duration, tags ...any) Trigger[T] {
+	return TriggerFunc[T](func(conductor Conductor[T], stop <-chan struct{}) <-chan any {
+		out := make(chan any)
+
+		scope := triggerScope(conductor)
+
+		var mu sync.Mutex
+		broadcast := time.Now()
+		last := make(map[any]time.Time)
+		fired := make(map[any]time.Time)
+
+		unhook := hubOf(conductor).addHook(func(env Envelope[T]) {
+			if env.Origin == OriginTrigger {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			now := time.Now()
+			if len(env.Tags) == 0 {
+				broadcast = now
+				return
+			}
+			for _, tag := range env.Tags {
+				if name, ok := scope(tag); ok {
+					last[name] = now
+				}
+			}
+		})
+
+		// XXX: the tags to watch are looked up at each tick when none is given,
+		// so that the ones registered later are considered too.
+		watched := func() []any {
+			if len(tags) > 0 {
+				return tags
+			}
+			if _, ok := any(unwrap(conductor)).(*tagged[T]); !ok {
+				return []any{nil}
+			}
+			var watched []any
+			for _, tag := range Tags(conductor) {
+				watched = append(watched, tag)
+			}
+			return watched
+		}
+
+		interval := d / 4
+		if interval <= 0 {
+			interval = time.Millisecond
+		}
+
+		go func() {
+			defer close(out)
+			defer unhook()
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-stop:
+					return
+				case now := <-ticker.C:
+					var idle []any
+					mu.Lock()
+					for _, tag := range watched() {
+						active := broadcast
+						if t, ok := last[tag]; ok && t.After(active) {
+							active = t
+						}
+						if now.Sub(active) < d || !fired[tag].Before(active) {
+							continue
+						}
+						fired[tag] = now
+						idle = append(idle, tag)
+					}
+					mu.Unlock()
+
+					for _, tag := range idle {
+						select {
+						case out <- tag:
+						case <-stop:
+							return
+						}
+					}
+				}
+			}
+		}()
+
+		return out
+	})
+}
+
+// FromChan is a [Trigger] firing each time an event is received from the given channel.
+// The event is the tag the [Policy] is asked about, or nil to fire to all the listeners.
+func FromChan[T any](ch <-chan any) Trigger[T] {
+	return TriggerFunc[T](func(_ Conductor[T], stop <-chan struct{}) <-chan any {
+		out := make(chan any)
+
+		go func() {
+			defer close(out)
+
+			for {
+				select {
+				case <-stop:
+					return
+				case ev, ok := <-ch:
+					if !ok {
+						return
+					}
+					select {
+					case out <- ev:
+					case <-stop:
+						return
+					}
+				}
+			}
+		}()
+
+		return out
+	})
+}
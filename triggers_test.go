@@ -0,0 +1,305 @@
+package conductor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOnListenersGone(t *testing.T) {
+	c := Tagged[string]()
+
+	supervisor := c.Cmd()
+	worker1 := WithTag(c, "pool", 1).Cmd()
+	worker2 := WithTag(c, "pool", 2).Cmd()
+	other := WithTag(c, "other").Cmd()
+
+	stop := On[string](c, ListenersGone[string]("pool"), SetPolicy(map[any]string{
+		"pool": "restart",
+	}))
+	defer stop()
+
+	Release[string](c, worker1)
+	Release[string](c, other)
+
+	select {
+	case cmd := <-supervisor:
+		t.Fatalf("Received %s before the last worker left", cmd)
+	case <-time.After(successTimeout):
+	}
+
+	Release[string](c, worker2)
+
+	select {
+	case cmd := <-supervisor:
+		if cmd != "restart" {
+			t.Fatalf("Unexpected: %s", cmd)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Restart not received")
+	}
+}
+
+// listenFrom asks for the channel of the listener always from the same place.
+//
+//go:noinline
+func listenFrom(c Conductor[string]) <-chan string {
+	return c.Cmd()
+}
+
+func TestOnEvicted(t *testing.T) {
+	c := Simple[string]()
+	EvictSlow[string](c, 10*time.Millisecond)
+
+	watch := make(chan struct{})
+	evicted := make(chan any)
+	go func() {
+		for tag := range Evicted[string]().Start(c, watch) {
+			evicted <- tag
+		}
+	}()
+	defer close(watch)
+
+	slow := listenFrom(c)
+
+	for i := 0; i < cmdBufSize-1; i++ {
+		Send[string](c)("fill")
+	}
+
+	replies := make(chan []any, 1)
+	go func() {
+		r, _ := Request[string](c)(context.Background(), "fill")
+		replies <- r
+	}()
+	for len(slow) < cmdBufSize {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The listener never reads, so it gets evicted once its buffer is full.
+	Send[string](c)("overflow")
+
+	select {
+	case tag := <-evicted:
+		if tag != nil {
+			t.Fatalf("Unexpected tag: %v", tag)
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Eviction not triggered")
+	}
+
+	if r := <-replies; len(r) != 1 || r[0] != ErrEvicted {
+		t.Fatalf("Unexpected replies: %v", r)
+	}
+
+	if Release[string](c, slow) {
+		t.Fatal("Evicted listener still registered")
+	}
+
+	// Asking again, it is told that it was evicted instead of being registered anew.
+	select {
+	case _, ok := <-listenFrom(c):
+		if ok {
+			t.Fatal("Evicted listener registered again")
+		}
+	case <-time.After(failureTimeout):
+		t.Fatal("Evicted listener registered again")
+	}
+}
+
+func TestOnIdleFor(t *testing.T) {
+	c := Tagged[string]()
+
+	supervisor := c.Cmd()
+	_ = WithTag(c, "busy").Cmd()
+	_ = WithTag(c, "quiet").Cmd()
+
+	stop := On[string](c, IdleFor[string](40*time.Millisecond), FuncPolicy(func(args ...any) (string, bool) {
+		return "idle " + args[0].(string), true
+	}))
+	defer stop()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				Send[string](c, "busy")("work")
+			}
+		}
+	}()
+
+	var idle []string
+	timeout := time.After(150 * time.Millisecond)
+loop:
+	for {
+		select {
+		case cmd := <-supervisor:
+			if cmd != "work" {
+				idle = append(idle, cmd)
+			}
+		case <-timeout:
+			break loop
+		}
+	}
+	close(done)
+
+	if len(idle) != 1 || idle[0] != "idle quiet" {
+		t.Fatalf("Unexpected: %v", idle)
+	}
+}
+
+func TestOnSubListenersGone(t *testing.T) {
+	c := Tagged[string]()
+	db := Sub(c, "db")
+
+	supervisor := db.Cmd()
+	root := WithTag(c, "primary").Cmd()
+	other := WithTag(c, "other").Cmd()
+	primary := WithTag(db, "primary").Cmd()
+
+	stopAll := On[string](db, ListenersGone[string](), FuncPolicy(func(args ...any) (string, bool) {
+		return "gone " + args[0].(string), true
+	}))
+	defer stopAll()
+	stopOne := On[string](db, ListenersGone[string]("primary"), SetPolicy(map[any]string{
+		"primary": "restart",
+	}))
+	defer stopOne()
+
+	// Outside of the namespace: not watched.
+	Release[string](c, other)
+
+	select {
+	case cmd := <-supervisor:
+		t.Fatalf("Received %s for a tag outside of the namespace", cmd)
+	case <-time.After(successTimeout):
+	}
+
+	Release[string](db, primary)
+
+	received := make(map[string]bool)
+	for len(received) < 2 {
+		select {
+		case cmd := <-supervisor:
+			received[cmd] = true
+		case <-time.After(failureTimeout):
+			t.Fatalf("Not fired: %v", received)
+		}
+	}
+	if !received["gone primary"] || !received["restart"] {
+		t.Fatalf("Unexpected: %v", received)
+	}
+
+	select {
+	case cmd := <-root:
+		t.Fatalf("Sent %s outside of the namespace", cmd)
+	case <-time.After(successTimeout):
+	}
+}
+
+func TestOnSubIdleFor(t *testing.T) {
+	c := Tagged[string]()
+	db := Sub(c, "db")
+
+	supervisor := db.Cmd()
+	root := WithTag(c, "primary").Cmd()
+	primary := WithTag(db, "primary").Cmd()
+
+	stop := On[string](db, IdleFor[string](40*time.Millisecond, "primary"), ConstantPolicy("idle"))
+	defer stop()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				Send[string](db, "primary")("work")
+				// Activity outside of the namespace does not count.
+				Send[string](c, "primary")("noise")
+			}
+		}
+	}()
+
+	var idle []string
+	timeout := time.After(150 * time.Millisecond)
+loop:
+	for {
+		select {
+		case cmd := <-primary:
+			if cmd != "work" {
+				idle = append(idle, cmd)
+			}
+		case <-supervisor:
+		case <-root:
+		case <-timeout:
+			break loop
+		}
+	}
+	close(done)
+
+	if len(idle) != 0 {
+		t.Fatalf("Fired while busy: %v", idle)
+	}
+
+	timeout = time.After(150 * time.Millisecond)
+	for fired := false; !fired; {
+		select {
+		case cmd := <-primary:
+			fired = cmd == "idle"
+		case <-timeout:
+			t.Fatal("Idle not fired")
+		}
+	}
+
+	for {
+		select {
+		case cmd := <-root:
+			if cmd != "noise" {
+				t.Fatalf("Sent %s outside of the namespace", cmd)
+			}
+		case <-time.After(successTimeout):
+			return
+		}
+	}
+}
+
+func TestOnFromChan(t *testing.T) {
+	c := Simple[string]()
+	lis := c.Cmd()
+
+	events := make(chan any)
+	stop := On[string](c, FromChan[string](events), SequencePolicy(
+		Step[string]{Cmd: "pause"},
+		Step[string]{Cmd: "resume", Delay: 5 * time.Millisecond},
+	))
+
+	events <- nil
+
+	for _, expected := range []string{"pause", "resume"} {
+		select {
+		case cmd := <-lis:
+			if cmd != expected {
+				t.Fatalf("Expected %s, got %s", expected, cmd)
+			}
+		case <-time.After(failureTimeout):
+			t.Fatalf("%s not received", expected)
+		}
+	}
+
+	stop()
+	time.Sleep(10 * time.Millisecond)
+
+	select {
+	case events <- nil:
+		t.Fatal("Trigger still running after stop")
+	case <-time.After(successTimeout):
+	}
+}
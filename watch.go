@@ -0,0 +1,152 @@
+package conductor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OriginPolicy is the origin of the commands fired by a [Policy].
+const OriginPolicy = "policy"
+
+// SenderKey is the key of the metadata of an [Envelope] holding the identity of its
+// sender, see [SendAs].
+const SenderKey = "sender"
+
+// Envelope is a command observed while being sent through a [Conductor]. Tags holds
+// the tags the command was addressed to, and is nil when the command is broadcast.
+// ID uniquely identifies the sending, while Origin tells where the command comes
+// from: it is empty for the commands sent with [Send] in this process. Meta holds the
+// metadata added by [SendAs] and by the [Interceptor]s, if any.
+type Envelope[T any] struct {
+	ID     string
+	Cmd    T
+	Tags   []any
+	Time   time.Time
+	Origin string
+	Meta   map[string]string
+}
+
+var (
+	envelopePrefix  = newEnvelopePrefix()
+	envelopeCounter atomic.Uint64
+)
+
+func newEnvelopePrefix() string {
+	var buf [6]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		panic(fmt.Sprintf("cannot generate envelope prefix: %v", err))
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+func newEnvelope[T any](cmd T, tags []any, origin string) Envelope[T] {
+	return Envelope[T]{
+		ID:     fmt.Sprintf("%s-%d", envelopePrefix, envelopeCounter.Add(1)),
+		Cmd:    cmd,
+		Tags:   append([]any(nil), tags...),
+		Time:   time.Now(),
+		Origin: origin,
+	}
+}
+
+// WithMeta returns a copy of the [Envelope] with the given metadata added, leaving the
+// metadata of the original one untouched.
+func (e Envelope[T]) WithMeta(key, value string) Envelope[T] {
+	meta := make(map[string]string, len(e.Meta)+1)
+	for k, v := range e.Meta {
+		meta[k] = v
+	}
+	meta[key] = value
+	e.Meta = meta
+	return e
+}
+
+// Watch returns a channel where every command sent through the given [Conductor] is
+// mirrored, together with a function to stop watching. The channel is closed once
+// the stop function is called. Watching never blocks the senders: if the receiving
+// side is too slow, envelopes are dropped. On a sub-conductor (see [Sub]), only the
+// commands sent to its namespace and the broadcast ones are mirrored.
+func Watch[T any](conductor Conductor[T]) (<-chan Envelope[T], func()) {
+	h := hubOf(conductor)
+	ch := h.addWatcher(sees(conductor))
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { h.removeWatcher(ch) })
+	}
+}
+
+// Follow returns a channel where every command sent through the given [Conductor] is
+// mirrored, like [Watch] does, together with a function to stop following. Unlike
+// watching, following never loses envelopes: the senders wait for the receiving side
+// to take them, so it must keep up. The channel is closed once the stop function is
+// called. On a sub-conductor, only its commands are mirrored, as [Watch] does.
+func Follow[T any](conductor Conductor[T]) (<-chan Envelope[T], func()) {
+	ch := make(chan Envelope[T], watchBufSize)
+	stopped := make(chan struct{})
+	sees := sees(conductor)
+
+	// XXX: the hook holds the read lock while sending, so that the channel is not
+	// closed under it.
+	var mu sync.RWMutex
+	var closed bool
+	remove := hubOf(conductor).addHook(func(env Envelope[T]) {
+		if !sees(env) {
+			return
+		}
+
+		mu.RLock()
+		defer mu.RUnlock()
+
+		if closed {
+			return
+		}
+		select {
+		case ch <- env:
+		case <-stopped:
+		}
+	})
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			remove()
+			close(stopped)
+
+			mu.Lock()
+			defer mu.Unlock()
+			closed = true
+			close(ch)
+		})
+	}
+}
+
+// sees returns the function telling if an [Envelope] observed on the hub of the given
+// [Conductor] concerns it: for a sub-conductor, only the ones sent to its namespace and
+// the broadcast ones do, otherwise all of them.
+func sees[T any](conductor Conductor[T]) func(Envelope[T]) bool {
+	if s, ok := subOf(conductor); ok {
+		return s.sees
+	}
+	return func(Envelope[T]) bool {
+		return true
+	}
+}
+
+// Deliver sends the command carried by the given [Envelope] to the listeners of its
+// tags, or to all of them if it has none, preserving its identity. It is meant to
+// re-inject commands that were observed elsewhere, e.g. on a [Conductor] living in
+// another process.
+func Deliver[T any](conductor Conductor[T], env Envelope[T]) {
+	switch c := any(unwrap(conductor)).(type) {
+	case *simple[T]:
+		c.dispatch(env)
+	case *tagged[T]:
+		c.dispatch(env)
+	default:
+		panic("conductor not supported")
+	}
+}